@@ -2,7 +2,9 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
 	"monkey/token"
+	"strings"
 )
 
 /*
@@ -87,6 +89,24 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// <target> = <value>、+= -= *= /= も同じノードで表す
+type AssignStatement struct {
+	Token token.Token // = や += などの演算子
+	Target Expression // *Identifier か *IndexExpression ex) x, arr[i], h["k"]
+	Operator string
+	Value Expression
+}
+func (as *AssignStatement) statementNode() {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(as.Target.String())
+	out.WriteString(" " + as.Operator + " ")
+	if as.Value != nil { out.WriteString(as.Value.String()) }
+	out.WriteString(";")
+	return out.String()
+}
+
 // 式文、x + 10;
 type ExpressionStatement struct { // Statementを実装することでProgramのStatementsスライスに追加できる
 	Token token.Token
@@ -107,6 +127,14 @@ func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string { return il.Token.Literal }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
 // !5 -15 ..etc
 type PrefixExpression struct {
 	Token token.Token
@@ -142,6 +170,25 @@ func (oe *InfixExpression) String() string {
 	return out.String()
 }
 
+// a && b, a || b、右辺は必要なときだけ評価する
+type LogicalExpression struct {
+	Token token.Token
+	Left Expression
+	Operator string
+	Right Expression
+}
+func (le *LogicalExpression) expressionNode() {}
+func (le *LogicalExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *LogicalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(le.Left.String())
+	out.WriteString(" " + le.Operator + " ")
+	out.WriteString(le.Right.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -150,6 +197,10 @@ func (b *Boolean) expressionNode() {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string { return b.Token.Literal }
 
+/*
+if (<condition>) <consequence> else <alternative>
+else if は、ifの式文一つだけを持つブロックをAlternativeにして表す（ブロックのTokenがif）
+*/
 type IfExpression struct { 
 	Token token.Token 
 	Condition Expression
@@ -160,13 +211,33 @@ func (ie *IfExpression) expressionNode() {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
-	out.WriteString("if")
+	out.WriteString("if ")
 	out.WriteString(ie.Condition.String())
-	out.WriteString("  ")
-	if ie.Alternative != nil { out.WriteString("else "); out.WriteString(ie.Alternative.String()) }
+	out.WriteString(" ")
+	out.WriteString(ie.Consequence.String())
+	if elseIf := ie.ElseIf(); elseIf != nil {
+		out.WriteString(" else ")
+		out.WriteString(elseIf.String())
+	} else if ie.Alternative != nil {
+		out.WriteString(" else ")
+		out.WriteString(ie.Alternative.String())
+	}
 	return out.String()
 }
 
+// else if と書かれていれば、その後ろのifを返す
+func (ie *IfExpression) ElseIf() *IfExpression {
+	if ie.Alternative == nil || ie.Alternative.Token.Type != token.IF || len(ie.Alternative.Statements) != 1 {
+		return nil
+	}
+	stmt, ok := ie.Alternative.Statements[0].(*ExpressionStatement)
+	if !ok {
+		return nil
+	}
+	elseIf, _ := stmt.Expression.(*IfExpression)
+	return elseIf
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
@@ -182,4 +253,239 @@ func (bs *BlockStatement) String() string {
 	}
 
 	return out.String()
-}
\ No newline at end of file
+}
+
+// fn(<parameters>) <block statement>
+type FunctionLiteral struct {
+	Token      token.Token // fn
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+func (fl *FunctionLiteral) expressionNode() {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range fl.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fl.Body.String())
+	return out.String()
+}
+
+// macro(<parameters>) <block statement>、引数も戻り値もquoteされたAST
+type MacroLiteral struct {
+	Token      token.Token // macro
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+func (ml *MacroLiteral) expressionNode() {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+	return out.String()
+}
+
+// <expression>(<comma separated expressions>)
+type CallExpression struct {
+	Token     token.Token // (
+	Function  Expression  // 識別子か関数リテラル
+	Arguments []Expression
+}
+func (ce *CallExpression) expressionNode() {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+	args := []string{}
+	for _, a := range ce.Arguments {
+		args = append(args, a.String())
+	}
+	out.WriteString(ce.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+	return out.String()
+}
+
+type StringLiteral struct {
+	Token token.Token
+	Value string // エスケープを展開した中身
+}
+func (sl *StringLiteral) expressionNode() {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string { return QuoteString(sl.Value) }
+
+// ソースに書ける形の文字列リテラルに戻す
+func QuoteString(s string) string {
+	var out bytes.Buffer
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				out.WriteString(fmt.Sprintf(`\u{%x}`, r))
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+// [<expression>, <expression>, ...]
+type ArrayLiteral struct {
+	Token    token.Token // [
+	Elements []Expression
+}
+func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+// <expression>[<expression>]
+type IndexExpression struct {
+	Token token.Token // [
+	Left  Expression
+	Index Expression
+}
+func (ie *IndexExpression) expressionNode() {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+	return out.String()
+}
+
+// {<expression>: <expression>, ...}、書かれた順に並べておく
+type HashLiteral struct {
+	Token token.Token // {
+	Pairs []*HashPair
+}
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// while (<condition>) <body>
+type WhileStatement struct {
+	Token     token.Token // while
+	Condition Expression
+	Body      *BlockStatement
+}
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("while ")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+	return out.String()
+}
+
+// for (<init>; <condition>; <post>) <body>、どれも省略できる
+type ForStatement struct {
+	Token     token.Token // for
+	Init      Statement
+	Condition Expression
+	Post      Statement
+	Body      *BlockStatement
+}
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fs.Init != nil { out.WriteString(strings.TrimSuffix(fs.Init.String(), ";")) }
+	out.WriteString("; ")
+	if fs.Condition != nil { out.WriteString(fs.Condition.String()) }
+	out.WriteString("; ")
+	if fs.Post != nil { out.WriteString(strings.TrimSuffix(fs.Post.String(), ";")) }
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+// for (<variable> in <iterable>) <body>
+type ForInStatement struct {
+	Token    token.Token // for
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+func (fi *ForInStatement) statementNode() {}
+func (fi *ForInStatement) TokenLiteral() string { return fi.Token.Literal }
+func (fi *ForInStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	out.WriteString(fi.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fi.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fi.Body.String())
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // break
+}
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string { return bs.Token.Literal + ";" }
+
+type ContinueStatement struct {
+	Token token.Token // continue
+}
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string { return cs.Token.Literal + ";" }
@@ -0,0 +1,531 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey/token"
+)
+
+/*
+ASTとJSONの相互変換
+ノードは {"kind":"InfixExpression","token":{...},"left":{...},...} の形で、kindはGoの型名
+トークンの位置とコメントも持つので、Decodeすると元と同じ木（Stringも同じ）に戻る
+省略されている子（nil）はキーごと出さない
+*/
+
+type jsonNode struct {
+	Kind  string     `json:"kind"`
+	Token *jsonToken `json:"token,omitempty"`
+
+	Name        *jsonNode       `json:"name,omitempty"`
+	Target      *jsonNode       `json:"target,omitempty"`
+	Operator    string          `json:"operator,omitempty"`
+	Value       json.RawMessage `json:"value,omitempty"` // リテラルなら値そのもの、それ以外はノード
+	Expression  *jsonNode       `json:"expression,omitempty"`
+	Left        *jsonNode       `json:"left,omitempty"`
+	Right       *jsonNode       `json:"right,omitempty"`
+	Init        *jsonNode       `json:"init,omitempty"`
+	Condition   *jsonNode       `json:"condition,omitempty"`
+	Post        *jsonNode       `json:"post,omitempty"`
+	Consequence *jsonNode       `json:"consequence,omitempty"`
+	Alternative *jsonNode       `json:"alternative,omitempty"`
+	Variable    *jsonNode       `json:"variable,omitempty"`
+	Iterable    *jsonNode       `json:"iterable,omitempty"`
+	Function    *jsonNode       `json:"function,omitempty"`
+	Index       *jsonNode       `json:"index,omitempty"`
+	Parameters  []*jsonNode     `json:"parameters,omitempty"`
+	Arguments   []*jsonNode     `json:"arguments,omitempty"`
+	Elements    []*jsonNode     `json:"elements,omitempty"`
+	Pairs       []*jsonPair     `json:"pairs,omitempty"`
+	Statements  []*jsonNode     `json:"statements,omitempty"`
+	Body        *jsonNode       `json:"body,omitempty"`
+}
+
+type jsonPair struct {
+	Key   *jsonNode `json:"key"`
+	Value *jsonNode `json:"value"`
+}
+
+type jsonToken struct {
+	Type     token.TokenType `json:"type"`
+	Literal  string          `json:"literal"`
+	Pos      *jsonPos        `json:"pos,omitempty"`
+	Leading  []jsonComment   `json:"leading,omitempty"`
+	Trailing []jsonComment   `json:"trailing,omitempty"`
+}
+
+type jsonPos struct {
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Offset   int    `json:"offset"`
+}
+
+type jsonComment struct {
+	Text string   `json:"text"`
+	Pos  *jsonPos `json:"pos,omitempty"`
+}
+
+// ノード（普通は*Program）をJSONにする
+func Encode(node Node) ([]byte, error) {
+	n, err := encodeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(n)
+}
+
+// Encodeした形からノードに戻す
+func Decode(data []byte) (Node, error) {
+	var n jsonNode
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return decodeNode(&n)
+}
+
+func encodeNode(node Node) (*jsonNode, error) {
+	var err error
+	n := &jsonNode{Kind: kindOf(node)}
+
+	// 子のエンコードのエラーは最初のものだけ覚えておく
+	child := func(c Node) *jsonNode {
+		if err != nil || isNilNode(c) {
+			return nil
+		}
+		var encoded *jsonNode
+		encoded, err = encodeNode(c)
+		return encoded
+	}
+	value := func(v interface{}) json.RawMessage {
+		if err != nil {
+			return nil
+		}
+		var raw []byte
+		raw, err = json.Marshal(v)
+		return raw
+	}
+	nodeValue := func(c Node) json.RawMessage {
+		if isNilNode(c) {
+			return nil
+		}
+		return value(child(c))
+	}
+
+	switch node := node.(type) {
+	// 文
+	case *Program:
+		n.Statements = encodeStatements(node.Statements, child)
+	case *LetStatement:
+		n.Token = encodeToken(node.Token)
+		n.Name = child(node.Name)
+		n.Value = nodeValue(node.Value)
+	case *ReturnStatement:
+		n.Token = encodeToken(node.Token)
+		n.Value = nodeValue(node.Value)
+	case *AssignStatement:
+		n.Token = encodeToken(node.Token)
+		n.Target = child(node.Target)
+		n.Operator = node.Operator
+		n.Value = nodeValue(node.Value)
+	case *ExpressionStatement:
+		n.Token = encodeToken(node.Token)
+		n.Expression = child(node.Expression)
+	case *BlockStatement:
+		n.Token = encodeToken(node.Token)
+		n.Statements = encodeStatements(node.Statements, child)
+	case *WhileStatement:
+		n.Token = encodeToken(node.Token)
+		n.Condition = child(node.Condition)
+		n.Body = child(node.Body)
+	case *ForStatement:
+		n.Token = encodeToken(node.Token)
+		n.Init = child(node.Init)
+		n.Condition = child(node.Condition)
+		n.Post = child(node.Post)
+		n.Body = child(node.Body)
+	case *ForInStatement:
+		n.Token = encodeToken(node.Token)
+		n.Variable = child(node.Variable)
+		n.Iterable = child(node.Iterable)
+		n.Body = child(node.Body)
+	case *BreakStatement:
+		n.Token = encodeToken(node.Token)
+	case *ContinueStatement:
+		n.Token = encodeToken(node.Token)
+
+	// 式
+	case *Identifier:
+		n.Token = encodeToken(node.Token)
+		n.Value = value(node.Value)
+	case *IntegerLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Value = value(node.Value)
+	case *FloatLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Value = value(node.Value)
+	case *StringLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Value = value(node.Value)
+	case *Boolean:
+		n.Token = encodeToken(node.Token)
+		n.Value = value(node.Value)
+	case *PrefixExpression:
+		n.Token = encodeToken(node.Token)
+		n.Operator = node.Operator
+		n.Right = child(node.Right)
+	case *InfixExpression:
+		n.Token = encodeToken(node.Token)
+		n.Operator = node.Operator
+		n.Left = child(node.Left)
+		n.Right = child(node.Right)
+	case *LogicalExpression:
+		n.Token = encodeToken(node.Token)
+		n.Operator = node.Operator
+		n.Left = child(node.Left)
+		n.Right = child(node.Right)
+	case *IfExpression:
+		n.Token = encodeToken(node.Token)
+		n.Condition = child(node.Condition)
+		n.Consequence = child(node.Consequence)
+		n.Alternative = child(node.Alternative)
+	case *FunctionLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Parameters = encodeIdentifiers(node.Parameters, child)
+		n.Body = child(node.Body)
+	case *MacroLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Parameters = encodeIdentifiers(node.Parameters, child)
+		n.Body = child(node.Body)
+	case *CallExpression:
+		n.Token = encodeToken(node.Token)
+		n.Function = child(node.Function)
+		n.Arguments = encodeExpressions(node.Arguments, child)
+	case *ArrayLiteral:
+		n.Token = encodeToken(node.Token)
+		n.Elements = encodeExpressions(node.Elements, child)
+	case *IndexExpression:
+		n.Token = encodeToken(node.Token)
+		n.Left = child(node.Left)
+		n.Index = child(node.Index)
+	case *HashLiteral:
+		n.Token = encodeToken(node.Token)
+		for _, pair := range node.Pairs {
+			n.Pairs = append(n.Pairs, &jsonPair{Key: child(pair.Key), Value: child(pair.Value)})
+		}
+
+	default:
+		return nil, fmt.Errorf("ast: cannot encode node type %T", node)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func encodeStatements(list []Statement, child func(Node) *jsonNode) []*jsonNode {
+	nodes := []*jsonNode{}
+	for _, stmt := range list {
+		nodes = append(nodes, child(stmt))
+	}
+	return nodes
+}
+
+func encodeExpressions(list []Expression, child func(Node) *jsonNode) []*jsonNode {
+	nodes := []*jsonNode{}
+	for _, exp := range list {
+		nodes = append(nodes, child(exp))
+	}
+	return nodes
+}
+
+func encodeIdentifiers(list []*Identifier, child func(Node) *jsonNode) []*jsonNode {
+	nodes := []*jsonNode{}
+	for _, ident := range list {
+		nodes = append(nodes, child(ident))
+	}
+	return nodes
+}
+
+func encodeToken(tok token.Token) *jsonToken {
+	t := &jsonToken{Type: tok.Type, Literal: tok.Literal, Pos: encodePos(tok.Pos)}
+	for _, c := range tok.Leading {
+		t.Leading = append(t.Leading, jsonComment{Text: c.Text, Pos: encodePos(c.Pos)})
+	}
+	for _, c := range tok.Trailing {
+		t.Trailing = append(t.Trailing, jsonComment{Text: c.Text, Pos: encodePos(c.Pos)})
+	}
+	return t
+}
+
+// 位置を持っていなければ出さない
+func encodePos(pos token.Pos) *jsonPos {
+	if pos == (token.Pos{}) {
+		return nil
+	}
+	return &jsonPos{Filename: pos.Filename, Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
+
+// *ast.InfixExpression なら InfixExpression
+func kindOf(node Node) string {
+	name := fmt.Sprintf("%T", node)
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '.' {
+			return name[i+1:]
+		}
+	}
+	return name
+}
+
+// インターフェースに入った型付きのnilもnilとして扱う
+func isNilNode(node Node) bool {
+	if node == nil {
+		return true
+	}
+	switch n := node.(type) {
+	case *BlockStatement:
+		return n == nil
+	case *Identifier:
+		return n == nil
+	}
+	return false
+}
+
+func decodeNode(n *jsonNode) (Node, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	var err error
+	tok := decodeToken(n.Token)
+
+	// 子のデコード、最初のエラーだけ覚えておく
+	expression := func(c *jsonNode) Expression {
+		if err != nil || c == nil {
+			return nil
+		}
+		var exp Expression
+		exp, err = decodeExpression(c)
+		return exp
+	}
+	statement := func(c *jsonNode) Statement {
+		if err != nil || c == nil {
+			return nil
+		}
+		var stmt Statement
+		stmt, err = decodeStatement(c)
+		return stmt
+	}
+	block := func(c *jsonNode) *BlockStatement {
+		if err != nil || c == nil {
+			return nil
+		}
+		var b *BlockStatement
+		b, err = decodeBlock(c)
+		return b
+	}
+	identifier := func(c *jsonNode) *Identifier {
+		if err != nil || c == nil {
+			return nil
+		}
+		var ident *Identifier
+		ident, err = decodeIdentifier(c)
+		return ident
+	}
+	// valueがリテラルの値のとき
+	scalar := func(v interface{}) {
+		if err == nil {
+			err = json.Unmarshal(n.Value, v)
+		}
+	}
+	// valueがノードのとき、ないこともある
+	valueExpression := func() Expression {
+		if err != nil || len(n.Value) == 0 || string(n.Value) == "null" {
+			return nil
+		}
+		var c jsonNode
+		if err = json.Unmarshal(n.Value, &c); err != nil {
+			return nil
+		}
+		return expression(&c)
+	}
+
+	var node Node
+	switch n.Kind {
+	// 文
+	case "Program":
+		program := &Program{Statements: []Statement{}}
+		for _, s := range n.Statements {
+			program.Statements = append(program.Statements, statement(s))
+		}
+		node = program
+	case "LetStatement":
+		node = &LetStatement{Token: tok, Name: identifier(n.Name), Value: valueExpression()}
+	case "ReturnStatement":
+		node = &ReturnStatement{Token: tok, Value: valueExpression()}
+	case "AssignStatement":
+		node = &AssignStatement{Token: tok, Target: expression(n.Target), Operator: n.Operator, Value: valueExpression()}
+	case "ExpressionStatement":
+		node = &ExpressionStatement{Token: tok, Expression: expression(n.Expression)}
+	case "BlockStatement":
+		b := &BlockStatement{Token: tok, Statements: []Statement{}}
+		for _, s := range n.Statements {
+			b.Statements = append(b.Statements, statement(s))
+		}
+		node = b
+	case "WhileStatement":
+		node = &WhileStatement{Token: tok, Condition: expression(n.Condition), Body: block(n.Body)}
+	case "ForStatement":
+		node = &ForStatement{Token: tok, Init: statement(n.Init), Condition: expression(n.Condition),
+			Post: statement(n.Post), Body: block(n.Body)}
+	case "ForInStatement":
+		node = &ForInStatement{Token: tok, Variable: identifier(n.Variable), Iterable: expression(n.Iterable),
+			Body: block(n.Body)}
+	case "BreakStatement":
+		node = &BreakStatement{Token: tok}
+	case "ContinueStatement":
+		node = &ContinueStatement{Token: tok}
+
+	// 式
+	case "Identifier":
+		ident := &Identifier{Token: tok}
+		scalar(&ident.Value)
+		node = ident
+	case "IntegerLiteral":
+		lit := &IntegerLiteral{Token: tok}
+		scalar(&lit.Value)
+		node = lit
+	case "FloatLiteral":
+		lit := &FloatLiteral{Token: tok}
+		scalar(&lit.Value)
+		node = lit
+	case "StringLiteral":
+		lit := &StringLiteral{Token: tok}
+		scalar(&lit.Value)
+		node = lit
+	case "Boolean":
+		lit := &Boolean{Token: tok}
+		scalar(&lit.Value)
+		node = lit
+	case "PrefixExpression":
+		node = &PrefixExpression{Token: tok, Operator: n.Operator, Right: expression(n.Right)}
+	case "InfixExpression":
+		node = &InfixExpression{Token: tok, Operator: n.Operator, Left: expression(n.Left), Right: expression(n.Right)}
+	case "LogicalExpression":
+		node = &LogicalExpression{Token: tok, Operator: n.Operator, Left: expression(n.Left), Right: expression(n.Right)}
+	case "IfExpression":
+		node = &IfExpression{Token: tok, Condition: expression(n.Condition), Consequence: block(n.Consequence),
+			Alternative: block(n.Alternative)}
+	case "FunctionLiteral":
+		lit := &FunctionLiteral{Token: tok, Parameters: []*Identifier{}, Body: block(n.Body)}
+		for _, p := range n.Parameters {
+			lit.Parameters = append(lit.Parameters, identifier(p))
+		}
+		node = lit
+	case "MacroLiteral":
+		lit := &MacroLiteral{Token: tok, Parameters: []*Identifier{}, Body: block(n.Body)}
+		for _, p := range n.Parameters {
+			lit.Parameters = append(lit.Parameters, identifier(p))
+		}
+		node = lit
+	case "CallExpression":
+		call := &CallExpression{Token: tok, Function: expression(n.Function), Arguments: []Expression{}}
+		for _, a := range n.Arguments {
+			call.Arguments = append(call.Arguments, expression(a))
+		}
+		node = call
+	case "ArrayLiteral":
+		array := &ArrayLiteral{Token: tok, Elements: []Expression{}}
+		for _, e := range n.Elements {
+			array.Elements = append(array.Elements, expression(e))
+		}
+		node = array
+	case "IndexExpression":
+		node = &IndexExpression{Token: tok, Left: expression(n.Left), Index: expression(n.Index)}
+	case "HashLiteral":
+		hash := &HashLiteral{Token: tok, Pairs: []*HashPair{}}
+		for _, pair := range n.Pairs {
+			if pair == nil {
+				return nil, fmt.Errorf("ast: null pair in HashLiteral")
+			}
+			hash.Pairs = append(hash.Pairs, &HashPair{Key: expression(pair.Key), Value: expression(pair.Value)})
+		}
+		node = hash
+
+	default:
+		return nil, fmt.Errorf("ast: unknown node kind %q", n.Kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func decodeExpression(n *jsonNode) (Expression, error) {
+	node, err := decodeNode(n)
+	if err != nil {
+		return nil, err
+	}
+	exp, ok := node.(Expression)
+	if !ok {
+		return nil, fmt.Errorf("ast: %s is not an expression", n.Kind)
+	}
+	return exp, nil
+}
+
+func decodeStatement(n *jsonNode) (Statement, error) {
+	node, err := decodeNode(n)
+	if err != nil {
+		return nil, err
+	}
+	stmt, ok := node.(Statement)
+	if !ok {
+		return nil, fmt.Errorf("ast: %s is not a statement", n.Kind)
+	}
+	return stmt, nil
+}
+
+func decodeBlock(n *jsonNode) (*BlockStatement, error) {
+	if n.Kind != "BlockStatement" {
+		return nil, fmt.Errorf("ast: expected BlockStatement, got %s", n.Kind)
+	}
+	node, err := decodeNode(n)
+	if err != nil {
+		return nil, err
+	}
+	return node.(*BlockStatement), nil
+}
+
+func decodeIdentifier(n *jsonNode) (*Identifier, error) {
+	if n.Kind != "Identifier" {
+		return nil, fmt.Errorf("ast: expected Identifier, got %s", n.Kind)
+	}
+	node, err := decodeNode(n)
+	if err != nil {
+		return nil, err
+	}
+	return node.(*Identifier), nil
+}
+
+func decodeToken(t *jsonToken) token.Token {
+	if t == nil {
+		return token.Token{}
+	}
+	tok := token.Token{Type: t.Type, Literal: t.Literal, Pos: decodePos(t.Pos)}
+	for _, c := range t.Leading {
+		tok.Leading = append(tok.Leading, token.Comment{Text: c.Text, Pos: decodePos(c.Pos)})
+	}
+	for _, c := range t.Trailing {
+		tok.Trailing = append(tok.Trailing, token.Comment{Text: c.Text, Pos: decodePos(c.Pos)})
+	}
+	return tok
+}
+
+func decodePos(p *jsonPos) token.Pos {
+	if p == nil {
+		return token.Pos{}
+	}
+	return token.Pos{Filename: p.Filename, Line: p.Line, Column: p.Column, Offset: p.Offset}
+}
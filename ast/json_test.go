@@ -0,0 +1,118 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `// 先頭のコメント
+let add = fn(a, b) { a + b }; // 足し算
+let xs = [1, 2.5, "s\n", true, !false, -3];
+let h = {"k": xs[0], 1: add(1, 2)};
+let m = macro(x) { quote(unquote(x) * 2) };
+if (a < b && c || !d) { return; } else if (e) { return 1 } else { /* 空 */ }
+while (x >= 0) { x -= 1; if (x == 3) { break; } continue; }
+for (let i = 0; i < 10; i += 1) { h["k"] = i ** 2 % 3; }
+for (;;) { break }
+for (c in "abc") { puts(c) }
+`
+
+	l := lexer.NewFile("test.monkey", input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has errors: %q", p.Errors())
+	}
+
+	data, err := ast.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	decoded, err := ast.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+
+	if _, ok := decoded.(*ast.Program); !ok {
+		t.Fatalf("decoded is not *ast.Program. got=%T", decoded)
+	}
+	if decoded.String() != program.String() {
+		t.Errorf("String() differs.\nwant=%q\ngot= %q", program.String(), decoded.String())
+	}
+	if !reflect.DeepEqual(decoded, program) {
+		t.Errorf("decoded tree is not equal to the original")
+	}
+
+	// もう一度エンコードしても同じになる
+	again, err := ast.Encode(decoded)
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("re-encoded JSON differs")
+	}
+}
+
+func TestEncodeShape(t *testing.T) {
+	program := parse(t, "x + 1")
+
+	data, err := ast.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	expected := `{"kind":"Program","statements":[{"kind":"ExpressionStatement",` +
+		`"token":{"type":"IDENT","literal":"x","pos":{"line":1,"column":1,"offset":0}},` +
+		`"expression":{"kind":"InfixExpression",` +
+		`"token":{"type":"+","literal":"+","pos":{"line":1,"column":3,"offset":2}},"operator":"+",` +
+		`"left":{"kind":"Identifier","token":{"type":"IDENT","literal":"x","pos":{"line":1,"column":1,"offset":0}},"value":"x"},` +
+		`"right":{"kind":"IntegerLiteral","token":{"type":"INT","literal":"1","pos":{"line":1,"column":5,"offset":4}},"value":1}}}]}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot= %s", expected, data)
+	}
+
+	// 省略されている子はキーごと出さない
+	data, _ = ast.Encode(parse(t, "return;"))
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %s", err)
+	}
+	stmt := decoded["statements"].([]interface{})[0].(map[string]interface{})
+	if _, ok := stmt["value"]; ok {
+		t.Errorf("return without value has a value key: %s", data)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Nope"}`, `ast: unknown node kind "Nope"`},
+		{`{"kind":"Program","statements":[{"kind":"Identifier","value":"x"}]}`, "ast: Identifier is not a statement"},
+		{`{"kind":"PrefixExpression","operator":"-","right":{"kind":"BreakStatement"}}`, "ast: BreakStatement is not an expression"},
+		{`{"kind":"WhileStatement","condition":{"kind":"Boolean","value":true},"body":{"kind":"Identifier"}}`,
+			"ast: expected BlockStatement, got Identifier"},
+		{`{"kind":"LetStatement","name":{"kind":"IntegerLiteral","value":1}}`, "ast: expected Identifier, got IntegerLiteral"},
+		{`{"kind":"IntegerLiteral","value":"one"}`, "cannot unmarshal"},
+		{`[`, "unexpected end of JSON input"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.Decode([]byte(tt.input))
+		if err == nil {
+			t.Errorf("no error for %s", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %s. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
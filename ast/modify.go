@@ -0,0 +1,151 @@
+package ast
+
+// 子を書き換えてから自分をfに渡す（葉から根に向かって書き換わる）
+type ModifierFunc func(Node) Node
+
+/*
+木をたどって、各ノードをfの返したノードに置き換える
+元の木は変えずに、子を持つノードは作り直す（同じ木を何度書き換えてもいい）
+置き換え先が入らない型ならnilになる
+letの名前や関数の引数など、束縛する側の識別子は置き換えない
+*/
+func Modify(node Node, fn ModifierFunc) Node {
+	switch n := node.(type) {
+	// 文
+	case *Program:
+		c := *n
+		c.Statements = modifyStatements(n.Statements, fn)
+		node = &c
+	case *LetStatement:
+		c := *n
+		if n.Value != nil {
+			c.Value, _ = Modify(n.Value, fn).(Expression)
+		}
+		node = &c
+	case *ReturnStatement:
+		c := *n
+		if n.Value != nil {
+			c.Value, _ = Modify(n.Value, fn).(Expression)
+		}
+		node = &c
+	case *AssignStatement:
+		c := *n
+		c.Target, _ = Modify(n.Target, fn).(Expression)
+		if n.Value != nil {
+			c.Value, _ = Modify(n.Value, fn).(Expression)
+		}
+		node = &c
+	case *ExpressionStatement:
+		c := *n
+		if n.Expression != nil {
+			c.Expression, _ = Modify(n.Expression, fn).(Expression)
+		}
+		node = &c
+	case *BlockStatement:
+		c := *n
+		c.Statements = modifyStatements(n.Statements, fn)
+		node = &c
+	case *WhileStatement:
+		c := *n
+		c.Condition, _ = Modify(n.Condition, fn).(Expression)
+		c.Body, _ = Modify(n.Body, fn).(*BlockStatement)
+		node = &c
+	case *ForStatement:
+		c := *n
+		if n.Init != nil {
+			c.Init, _ = Modify(n.Init, fn).(Statement)
+		}
+		if n.Condition != nil {
+			c.Condition, _ = Modify(n.Condition, fn).(Expression)
+		}
+		if n.Post != nil {
+			c.Post, _ = Modify(n.Post, fn).(Statement)
+		}
+		c.Body, _ = Modify(n.Body, fn).(*BlockStatement)
+		node = &c
+	case *ForInStatement:
+		c := *n
+		c.Iterable, _ = Modify(n.Iterable, fn).(Expression)
+		c.Body, _ = Modify(n.Body, fn).(*BlockStatement)
+		node = &c
+
+	// 式
+	case *PrefixExpression:
+		c := *n
+		c.Right, _ = Modify(n.Right, fn).(Expression)
+		node = &c
+	case *InfixExpression:
+		c := *n
+		c.Left, _ = Modify(n.Left, fn).(Expression)
+		c.Right, _ = Modify(n.Right, fn).(Expression)
+		node = &c
+	case *LogicalExpression:
+		c := *n
+		c.Left, _ = Modify(n.Left, fn).(Expression)
+		c.Right, _ = Modify(n.Right, fn).(Expression)
+		node = &c
+	case *IfExpression:
+		c := *n
+		c.Condition, _ = Modify(n.Condition, fn).(Expression)
+		c.Consequence, _ = Modify(n.Consequence, fn).(*BlockStatement)
+		if n.Alternative != nil {
+			c.Alternative, _ = Modify(n.Alternative, fn).(*BlockStatement)
+		}
+		node = &c
+	case *FunctionLiteral:
+		c := *n
+		c.Body, _ = Modify(n.Body, fn).(*BlockStatement)
+		node = &c
+	case *MacroLiteral:
+		c := *n
+		c.Body, _ = Modify(n.Body, fn).(*BlockStatement)
+		node = &c
+	case *CallExpression:
+		c := *n
+		c.Function, _ = Modify(n.Function, fn).(Expression)
+		c.Arguments = modifyExpressions(n.Arguments, fn)
+		node = &c
+	case *ArrayLiteral:
+		c := *n
+		c.Elements = modifyExpressions(n.Elements, fn)
+		node = &c
+	case *IndexExpression:
+		c := *n
+		c.Left, _ = Modify(n.Left, fn).(Expression)
+		c.Index, _ = Modify(n.Index, fn).(Expression)
+		node = &c
+	case *HashLiteral:
+		c := *n
+		c.Pairs = make([]*HashPair, len(n.Pairs))
+		for i, pair := range n.Pairs {
+			key, _ := Modify(pair.Key, fn).(Expression)
+			value, _ := Modify(pair.Value, fn).(Expression)
+			c.Pairs[i] = &HashPair{Key: key, Value: value}
+		}
+		node = &c
+	}
+
+	return fn(node)
+}
+
+func modifyStatements(list []Statement, fn ModifierFunc) []Statement {
+	if list == nil {
+		return nil
+	}
+	modified := make([]Statement, len(list))
+	for i, stmt := range list {
+		modified[i], _ = Modify(stmt, fn).(Statement)
+	}
+	return modified
+}
+
+func modifyExpressions(list []Expression, fn ModifierFunc) []Expression {
+	if list == nil {
+		return nil
+	}
+	modified := make([]Expression, len(list))
+	for i, exp := range list {
+		modified[i], _ = Modify(exp, fn).(Expression)
+	}
+	return modified
+}
@@ -0,0 +1,97 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+	"testing"
+)
+
+// 整数リテラル1を2に置き換える
+func turnOneIntoTwo(node ast.Node) ast.Node {
+	integer, ok := node.(*ast.IntegerLiteral)
+	if !ok || integer.Value != 1 {
+		return node
+	}
+	return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "2"}, Value: 2}
+}
+
+func TestModify(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1", "2"},
+		{"1 + 2", "(2 + 2)"},
+		{"2 + 1", "(2 + 2)"},
+		{"-1", "(-2)"},
+		{"1 && 1", "(2 && 2)"},
+		{"a[1]", "(a[2])"},
+		{"if (1) { 1 } else if (1) { 1 } else { 1 }", "if 2 2 else if 2 2 else 2"},
+		{"return 1;", "return 2;"},
+		{"let x = 1;", "let x = 2;"},
+		{"x += 1;", "x += 2;"},
+		{"a[1] = 1;", "(a[2]) = 2;"},
+		{"fn(x) { 1 }", "fn(x) 2"},
+		{"f(1, [1, 3])", "f(2, [2, 3])"},
+		{"{1: 1}", "{2: 2}"},
+		{"while (1) { 1 }", "while 2 2"},
+		{"for (let i = 1; i < 1; i += 1) { 1 }", "for (let i = 2; (i < 2); i += 2) 2"},
+		{"for (x in [1]) { 1 }", "for (x in [2]) 2"},
+		{"macro(x) { 1 }", "macro(x) 2"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		modified := ast.Modify(program, turnOneIntoTwo)
+		if modified.String() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, modified.String())
+		}
+	}
+}
+
+// 子から先に書き換わるので、入れ子の定数もまとめて畳み込める
+func TestModifyBottomUp(t *testing.T) {
+	fold := func(node ast.Node) ast.Node {
+		infix, ok := node.(*ast.InfixExpression)
+		if !ok || infix.Operator != "+" {
+			return node
+		}
+		left, ok := infix.Left.(*ast.IntegerLiteral)
+		if !ok {
+			return node
+		}
+		right, ok := infix.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return node
+		}
+		value := left.Value + right.Value
+		return &ast.IntegerLiteral{
+			Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10), Pos: infix.Token.Pos},
+			Value: value,
+		}
+	}
+
+	program := parse(t, "let x = 1 + 2 + (3 + 4); x + 1")
+	modified := ast.Modify(program, fold)
+
+	if modified.String() != "let x = 10;(x + 1)" {
+		t.Errorf("wrong result. got=%q", modified.String())
+	}
+}
+
+// 元の木はそのまま残る
+func TestModifyKeepsOriginal(t *testing.T) {
+	program := parse(t, "let x = 1 + [1, {1: 1}]; if (1) { f(1) } else if (1) { 1 }")
+	before := program.String()
+
+	modified := ast.Modify(program, turnOneIntoTwo)
+
+	if program.String() != before {
+		t.Errorf("original tree was changed. got=%q", program.String())
+	}
+	expected := "let x = (2 + [2, {2: 2}]);if 2 f(2) else if 2 2"
+	if modified.String() != expected {
+		t.Errorf("wrong result. expected=%q, got=%q", expected, modified.String())
+	}
+}
@@ -0,0 +1,122 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+// parserはastに依存しているので、外部テストパッケージで実際のソースから木を作る
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has errors: %q", p.Errors())
+	}
+	return program
+}
+
+// 訪れたノードの型を前順に並べる
+func nodeTypes(node ast.Node) []string {
+	var types []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			types = append(types, strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+		}
+		return true
+	})
+	return types
+}
+
+func TestInspectVisitsAllNodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = -1 + 2.5;", "Program LetStatement Identifier InfixExpression PrefixExpression IntegerLiteral FloatLiteral"},
+		{"return;", "Program ReturnStatement"},
+		{`x += "a"`, "Program AssignStatement Identifier StringLiteral"},
+		{"a[0] = true && b", "Program AssignStatement IndexExpression Identifier IntegerLiteral LogicalExpression Boolean Identifier"},
+		{"if (a) { b } else if (c) { d }",
+			"Program ExpressionStatement IfExpression Identifier BlockStatement ExpressionStatement Identifier " +
+				"BlockStatement ExpressionStatement IfExpression Identifier BlockStatement ExpressionStatement Identifier"},
+		{"fn(x, y) { f(x, [y]) }",
+			"Program ExpressionStatement FunctionLiteral Identifier Identifier BlockStatement ExpressionStatement " +
+				"CallExpression Identifier Identifier ArrayLiteral Identifier"},
+		{`{"k": v}`, "Program ExpressionStatement HashLiteral StringLiteral Identifier"},
+		{"while (a) { break; continue; }", "Program WhileStatement Identifier BlockStatement BreakStatement ContinueStatement"},
+		{"for (let i = 0; i < n; i += 1) { }",
+			"Program ForStatement LetStatement Identifier IntegerLiteral InfixExpression Identifier Identifier " +
+				"AssignStatement Identifier IntegerLiteral BlockStatement"},
+		{"for (;;) { }", "Program ForStatement BlockStatement"},
+		{"for (x in xs) { }", "Program ForInStatement Identifier Identifier BlockStatement"},
+		{"macro(a) { quote(a) }",
+			"Program ExpressionStatement MacroLiteral Identifier BlockStatement ExpressionStatement " +
+				"CallExpression Identifier Identifier"},
+	}
+
+	for _, tt := range tests {
+		got := strings.Join(nodeTypes(parse(t, tt.input)), " ")
+		if got != tt.expected {
+			t.Errorf("wrong nodes for %q.\nexpected=%s\ngot=     %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, "let f = fn(a) { a + 1 }; f(2) + 3;")
+
+	// 関数の中には入らずに整数リテラルを集める
+	var ints []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.IntegerLiteral:
+			ints = append(ints, n.String())
+		}
+		return true
+	})
+
+	if strings.Join(ints, ",") != "2,3" {
+		t.Errorf("wrong integer literals. got=%q", ints)
+	}
+}
+
+// Visitでnilを返したノードと、Visit(nil)の呼ばれ方を確かめる
+type depthVisitor struct {
+	depth int
+	max   *int
+	ends  *int
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.ends++
+		return nil
+	}
+	if v.depth > *v.max {
+		*v.max = v.depth
+	}
+	return depthVisitor{depth: v.depth + 1, max: v.max, ends: v.ends}
+}
+
+func TestWalk(t *testing.T) {
+	program := parse(t, "1 + (2 * 3)")
+
+	max, ends := 0, 0
+	ast.Walk(depthVisitor{max: &max, ends: &ends}, program)
+
+	// Program > ExpressionStatement > InfixExpression > InfixExpression > IntegerLiteral
+	if max != 4 {
+		t.Errorf("max depth wrong. expected=4, got=%d", max)
+	}
+	// 子をたどったノードごとに一回
+	if ends != 7 {
+		t.Errorf("Visit(nil) count wrong. expected=7, got=%d", ends)
+	}
+}
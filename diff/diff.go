@@ -0,0 +1,232 @@
+// diffは行単位のunified diffを作る（monkey fmt -d で使う）
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// 変更の前後に付ける変わっていない行の数
+const context = 3
+
+type op int
+
+const (
+	equal op = iota
+	del
+	ins
+)
+
+type line struct {
+	op   op
+	text string
+}
+
+// 同じなら空を返す
+func Unified(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	lines := diffLines(splitLines(string(old)), splitLines(string(new)))
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	// 変更のある行の前後contextまでを一つのハンクにまとめる
+	for start := 0; start < len(lines); {
+		for start < len(lines) && lines[start].op == equal {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+
+		from := max(start-context, 0)
+		end := start
+		for i := start; i < len(lines); i++ {
+			if lines[i].op != equal {
+				end = i + 1
+			} else if i-end >= 2*context {
+				break
+			}
+		}
+		to := min(end+context, len(lines))
+
+		writeHunk(&out, lines, from, to)
+		start = to
+	}
+
+	return out.Bytes()
+}
+
+func writeHunk(out *bytes.Buffer, lines []line, from, to int) {
+	// ハンクの先頭の行番号（1始まり）
+	oldStart, newStart := 1, 1
+	for _, l := range lines[:from] {
+		if l.op != ins {
+			oldStart++
+		}
+		if l.op != del {
+			newStart++
+		}
+	}
+
+	oldCount, newCount := 0, 0
+	var body bytes.Buffer
+	for _, l := range lines[from:to] {
+		switch l.op {
+		case equal:
+			oldCount++
+			newCount++
+			body.WriteString(" " + l.text)
+		case del:
+			oldCount++
+			body.WriteString("-" + l.text)
+		case ins:
+			newCount++
+			body.WriteString("+" + l.text)
+		}
+		if !strings.HasSuffix(l.text, "\n") {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	// 行数が0のときは直前の行番号を書く
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	out.Write(body.Bytes())
+}
+
+// 改行を残したまま行に分ける
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// 削除・追加・そのままの並びを作る
+func diffLines(a, b []string) []line {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.lines
+}
+
+/*
+Myersの差分アルゴリズムの線形メモリ版
+両端から同時に探して最短の編集の真ん中にある一致（middle snake）を見つけ、その前後を再帰的に比べる
+時間はO((N+M)D)、メモリはO(N+M)
+*/
+type differ struct {
+	a, b  []string
+	lines []line
+}
+
+// a[aLo:aHi] と b[bLo:bHi] の差分を足す
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// 前後の変わっていない行は先に取り除く
+	prefix := aLo
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	d.equal(prefix, aLo)
+
+	suffix := aHi
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for _, text := range d.b[bLo:bHi] {
+			d.lines = append(d.lines, line{ins, text})
+		}
+	case bLo == bHi:
+		for _, text := range d.a[aLo:aHi] {
+			d.lines = append(d.lines, line{del, text})
+		}
+	default:
+		// ここでは編集は二つ以上あるので、前後どちらも元より小さくなる
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		d.equal(x, u)
+		d.compare(u, aHi, v, bHi)
+	}
+
+	d.equal(aHi, suffix)
+}
+
+func (d *differ) equal(from, to int) {
+	for _, text := range d.a[from:to] {
+		d.lines = append(d.lines, line{equal, text})
+	}
+}
+
+/*
+最短の編集の真ん中にある一致 a[x:u] == b[y:v] を返す（空のこともある）
+vf[k]は前からたどって対角線k（x-y）で届いた一番遠いx
+vb[k]は後ろからたどったときの同じもの（末尾からの距離で持つ）
+*/
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	vf := make([]int, 2*offset+1)
+	vb := make([]int, 2*offset+1)
+
+	for e := 0; e <= maxD; e++ {
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1] // 追加
+			} else {
+				x = vf[offset+k-1] + 1 // 削除
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+
+			// 後ろからの対角線はdelta-k、前と後ろで合わせてnまで届けば重なっている
+			if odd && delta-k >= -(e-1) && delta-k <= e-1 && x+vb[offset+delta-k] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+
+			if !odd && delta-k >= -e && delta-k <= e && x+vf[offset+delta-k] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+	panic("diff: middle snake not found") // 両端からたどれば必ず重なる
+}
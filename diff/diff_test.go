@@ -0,0 +1,145 @@
+package diff
+
+import (
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		old      string
+		new      string
+		expected string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n",
+			"--- old\n+++ new\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -11,5 +12,3 @@\n 11\n 12\n 13\n-14\n-15\n",
+		},
+		{
+			"x",
+			"x\n",
+			"--- old\n+++ new\n@@ -1,1 +1,1 @@\n-x\n\\ No newline at end of file\n+x\n",
+		},
+		{
+			"a\nb\nc\nd\ne\n",
+			"a\nc\nd\nx\ne\n",
+			"--- old\n+++ new\n@@ -1,5 +1,5 @@\n a\n-b\n c\n d\n+x\n e\n",
+		},
+		{
+			"",
+			"a\n",
+			"--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+	}
+
+	for i, tt := range tests {
+		got := string(Unified("old", "new", []byte(tt.old), []byte(tt.new)))
+		if got != tt.expected {
+			t.Errorf("tests[%d] - wrong diff.\nexpected=%q\ngot=     %q", i, tt.expected, got)
+		}
+	}
+}
+
+// 変更が少なければ長いファイルでも行数の二乗の表は作らない
+func TestUnifiedLarge(t *testing.T) {
+	var old strings.Builder
+	for i := 0; i < 100000; i++ {
+		old.WriteString(strconv.Itoa(i) + "\n")
+	}
+	new := strings.Replace(old.String(), "\n10\n", "\nten\n", 1)
+	new = strings.Replace(new, "\n99990\n", "\nninety\n", 1)
+
+	expected := "--- old\n+++ new\n" +
+		"@@ -8,7 +8,7 @@\n 7\n 8\n 9\n-10\n+ten\n 11\n 12\n 13\n" +
+		"@@ -99988,7 +99988,7 @@\n 99987\n 99988\n 99989\n-99990\n+ninety\n 99991\n 99992\n 99993\n"
+	if got := string(Unified("old", "new", []byte(old.String()), []byte(new))); got != expected {
+		t.Errorf("wrong diff.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}
+
+// 全部の行が変わっても、メモリは行数の二乗にならない
+func TestUnifiedAllChanged(t *testing.T) {
+	var old, new strings.Builder
+	for i := 0; i < 4000; i++ {
+		old.WriteString("old " + strconv.Itoa(i) + "\n")
+		new.WriteString("new " + strconv.Itoa(i) + "\n")
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	out := Unified("old", "new", []byte(old.String()), []byte(new.String()))
+	runtime.ReadMemStats(&after)
+
+	if !strings.HasPrefix(string(out), "--- old\n+++ new\n@@ -1,4000 +1,4000 @@\n-old 0\n") {
+		t.Errorf("wrong diff. got=%q...", out[:60])
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64<<20 {
+		t.Errorf("too much memory allocated. got=%d bytes", alloc)
+	}
+}
+
+// 編集の数が最長共通部分列から求めたものと同じで、元の二つに戻せる
+func TestDiffLinesMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		lines := diffLines(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, l := range lines {
+			if l.op != ins {
+				gotA = append(gotA, l.text)
+			}
+			if l.op != del {
+				gotB = append(gotB, l.text)
+			}
+			if l.op != equal {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("diff of %q and %q does not reproduce them: %v", a, b, lines)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("diff of %q and %q is not minimal. expected=%d edits, got=%d", a, b, want, edits)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs[0][0]
+}
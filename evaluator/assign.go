@@ -0,0 +1,101 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"strings"
+)
+
+/*
+代入はletと違って新しい束縛を作らず、外側のスコープまでたどって書き換える
+配列とハッシュは要素をその場で書き換える（同じ配列を指す変数からも見える）
+値を持たない文なのでnilを返す
+*/
+
+func evalAssignStatement(as *ast.AssignStatement, env *object.Environment) object.Object {
+	switch target := as.Target.(type) {
+	case *ast.Identifier:
+		return evalIdentifierAssign(as, target, env)
+	case *ast.IndexExpression:
+		return evalIndexAssign(as, target, env)
+	default:
+		return newError("cannot assign to %s", as.Target.String())
+	}
+}
+
+func evalIdentifierAssign(as *ast.AssignStatement, target *ast.Identifier, env *object.Environment) object.Object {
+	current, ok := env.Get(target.Value)
+	if !ok {
+		return newError("assignment to undeclared identifier: %s", target.Value)
+	}
+
+	val := evalAssignValue(as, current, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	env.Assign(target.Value, val)
+	return nil
+}
+
+func evalIndexAssign(as *ast.AssignStatement, target *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+	index := Eval(target.Index, env)
+	if isAbrupt(index) {
+		return index
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d", idx.Value)
+		}
+
+		val := evalAssignValue(as, left.Elements[idx.Value], env)
+		if isAbrupt(val) {
+			return val
+		}
+		left.Elements[idx.Value] = val
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		// += などは既にあるキーにしか使えない
+		var current object.Object
+		if pair, ok := left.Pairs[key.HashKey()]; ok {
+			current = pair.Value
+		} else if as.Operator != "=" {
+			return newError("key not found: %s", index.Inspect())
+		}
+
+		val := evalAssignValue(as, current, env)
+		if isAbrupt(val) {
+			return val
+		}
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: val}
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return nil
+}
+
+// 右辺を評価する、+= なら今の値と + で計算した結果にする
+func evalAssignValue(as *ast.AssignStatement, current object.Object, env *object.Environment) object.Object {
+	val := Eval(as.Value, env)
+	if isAbrupt(val) || as.Operator == "=" {
+		return val
+	}
+	return evalInfixExpression(strings.TrimSuffix(as.Operator, "="), current, val)
+}
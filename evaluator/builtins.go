@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/object"
+	"unicode/utf8"
+)
+
+// 組み込み関数、同名の束縛があればそちらが優先される
+var builtins = map[string]*object.Builtin{
+	"len": {
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(len(arg.Pairs))}
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"first": {
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("first", args)
+			if err != nil {
+				return err
+			}
+			if len(arr.Elements) > 0 {
+				return arr.Elements[0]
+			}
+			return object.NULL
+		},
+	},
+	"last": {
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("last", args)
+			if err != nil {
+				return err
+			}
+			length := len(arr.Elements)
+			if length > 0 {
+				return arr.Elements[length-1]
+			}
+			return object.NULL
+		},
+	},
+	// 先頭以外の要素を持つ新しい配列
+	"rest": {
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("rest", args)
+			if err != nil {
+				return err
+			}
+			length := len(arr.Elements)
+			if length > 0 {
+				newElements := make([]object.Object, length-1)
+				copy(newElements, arr.Elements[1:length])
+				return &object.Array{Elements: newElements}
+			}
+			return object.NULL
+		},
+	},
+	// 元の配列は変えずに、末尾に追加した新しい配列を返す
+	"push": {
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			arr, err := arrayArgument("push", args[:1])
+			if err != nil {
+				return err
+			}
+			length := len(arr.Elements)
+			newElements := make([]object.Object, length+1)
+			copy(newElements, arr.Elements)
+			newElements[length] = args[1]
+			return &object.Array{Elements: newElements}
+		},
+	},
+	"puts": {
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			for _, arg := range args {
+				fmt.Fprintln(env.Output(), arg.Inspect())
+			}
+			return object.NULL
+		},
+	},
+}
+
+// 引数が配列一つだけであることを確かめる
+func arrayArgument(name string, args []object.Object) (*object.Array, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	return arr, nil
+}
@@ -0,0 +1,793 @@
+package evaluator
+
+import (
+	"bytes"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/token"
+	"testing"
+)
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"10", 10},
+		{"-5", -5},
+		{"-10", -10},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 + -50", 0},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"20 + 2 * -10", 0},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"3 * (3 * 3) + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"3 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"~5", -6},
+		{"1 + 2 << 1", 6},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"(1 > 2) == true", false},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{"1.5 >= 1", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && 0", true},
+		{"if (false) { 1 } || true", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBangOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!false", false},
+		{"!!5", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (true) {}", nil},
+		{"if (true) { let a = 1; }", nil},
+		{"if (false) { 10 } else {}", nil},
+		{"let x = if (true) {}; x", nil},
+		{"[if (true) {}][0]", nil},
+		{"let x = if (true) {}; if (x) { 10 } else { 20 }", 20},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{"if (10 > 1) { if (10 > 1) { return 10; } return 1; }", 10},
+		{"return;", nil},
+		{"if (true) { return } 9", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let a = 1; if (true) { let a = 2; } a;", 1},
+		{"let a = 1; if (true) { let b = a + 1; b }", 2},
+		{"let a = if (true) {}; len([a, a])", 2},
+		{"let a = if (true) { let b = 1; }; if (a == if (true) {}) { 3 }", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// 入れ子のブロックの中のreturnでも外側の文は実行されない
+// if (true) { if (true) { return 10; } return 1; } 9;
+func TestNestedReturn(t *testing.T) {
+	ret := func(v int64) ast.Statement {
+		return &ast.ReturnStatement{
+			Token: token.Token{Type: token.RETURN, Literal: "return"},
+			Value: &ast.IntegerLiteral{Token: token.Token{Type: token.INT}, Value: v},
+		}
+	}
+	ifTrue := func(stmts ...ast.Statement) ast.Statement {
+		return &ast.ExpressionStatement{
+			Expression: &ast.IfExpression{
+				Token:       token.Token{Type: token.IF, Literal: "if"},
+				Condition:   &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true},
+				Consequence: &ast.BlockStatement{Statements: stmts},
+			},
+		}
+	}
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			ifTrue(ifTrue(ret(10)), ret(1)),
+			&ast.ExpressionStatement{
+				Expression: &ast.IntegerLiteral{Token: token.Token{Type: token.INT}, Value: 9},
+			},
+		},
+	}
+
+	testIntegerObject(t, Eval(program, object.NewEnvironment()), 10)
+
+	// 式の中のブロックから出たreturnも、letや引数を通り抜けて関数から返る
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn() { let x = if (true) { return 5; }; 10 }; f()", 5},
+		{"let f = fn() { len([if (true) { return 5; }]); 10 }; f()", 5},
+		{"let f = fn() { let g = fn(x) { 10 }; g(if (true) { return 5; }); 10 }; f()", 5},
+		{"let f = fn() { 1 + if (true) { return 5; }; 10 }; f()", 5},
+		{"let f = fn() { let a = 0; a = if (true) { return 5; }; 10 }; f()", 5},
+		{"let f = fn() { return if (true) { return 5; }; }; f()", 5},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"let x = if (true) {}; x + 1", "type mismatch: NULL + INTEGER"},
+		{"let x = if (true) {}; -x", "unknown operator: -NULL"},
+		{"if (true) {} + 1", "type mismatch: NULL + INTEGER"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { if (10 > 1) { true + false; } 1 }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"foobar", "identifier not found: foobar"},
+		{"1 / 0", "division by zero"},
+		{"if (true) { let b = 1; } b", "identifier not found: b"},
+		{"fn(x) { x }(1, 2)", "wrong number of arguments: want=1, got=2"},
+		{"5(1)", "not a function: INTEGER"},
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`first(1)`, "argument to `first` must be ARRAY, got INTEGER"},
+		{`push([1])`, "wrong number of arguments. got=1, want=2"},
+		{`1[0]`, "index operator not supported: INTEGER"},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{`{[1]: 2}`, "unusable as hash key: ARRAY"},
+		{"5 % 0", "division by zero"},
+		{"1 << -1", "negative shift count: -1"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"1 & 1.5", "unknown operator: INTEGER & FLOAT"},
+		{"1 << 2.0", "unknown operator: INTEGER << FLOAT"},
+		{"1.5 | 2.5", "unknown operator: FLOAT | FLOAT"},
+		{"for (x in 5) { }", "cannot iterate over INTEGER"},
+		{"x = 1", "assignment to undeclared identifier: x"},
+		{"if (true) { let y = 1; } y += 1", "assignment to undeclared identifier: y"},
+		{"let x = 1; x += true", "type mismatch: INTEGER + BOOLEAN"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1"},
+		{`let a = [1]; a["0"] = 2`, "array index must be INTEGER, got STRING"},
+		{`let h = {}; h["k"] += 1`, "key not found: k"},
+		{"let h = {}; h[fn(x) { x }] = 1", "unusable as hash key: FUNCTION"},
+		{"let n = 1; n[0] = 2", "index assignment not supported: INTEGER"},
+		{"while (undefined) { }", "identifier not found: undefined"},
+		{"for (x in [1]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. Parameters=%+v", fn.Parameters)
+	}
+
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+
+	expectedBody := "(x + 2)"
+	if fn.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"let f = fn(x) { if (x > 1) { return 1; } return 2; }; f(5) + 10;", 11},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	input := `
+	let newAdder = fn(x) {
+		fn(y) { x + y };
+	};
+
+	let addTwo = newAdder(2);
+	addTwo(2);`
+
+	testIntegerObject(t, testEval(input), 4)
+}
+
+func TestRecursiveFunction(t *testing.T) {
+	input := `
+	let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+	fib(10);`
+
+	testIntegerObject(t, testEval(input), 55)
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	input := `"Hello" + " " + "World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+
+	testBooleanObject(t, testEval(`"a" + "b" == "ab"`), true)
+	testBooleanObject(t, testEval(`"a" != "a"`), false)
+}
+
+func TestBuiltinLen(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len("\u{1F600}")`, 1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+		{"let matrix = [[1, 2], [3, 4]]; matrix[1][0]", 3},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, nil},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([])`, nil},
+		{`push([], 1)`, []int{1}},
+		{`let a = [1]; let b = push(a, 2); len(a)`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestPutsOutput(t *testing.T) {
+	input := `let f = fn(x) { puts(x, "a") }; f(1); puts([1, 2])`
+
+	var out bytes.Buffer
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	env.SetOutput(&out)
+
+	evaluated := Eval(program, env)
+	testNullObject(t, evaluated)
+
+	expected := "1\na\n[1, 2]\n"
+	if out.String() != expected {
+		t.Errorf("output wrong. want=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		object.TRUE.HashKey():                      5,
+		object.FALSE.HashKey():                     6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{"a": 1, "a": 2}["a"]`, 2},
+		{`len({"a": 1, "b": 2})`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"1e3 - 1", 999.0},
+		{"7.5 % 2", 1.5},
+		{"7 % 2.5", 2.0},
+		{"2 ** 0.5 ** 2", 1.189207115002721},
+		{"2 ** -1", 0.5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		result, ok := evaluated.(*object.Float)
+		if !ok {
+			t.Errorf("object is not Float. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("object has wrong value. got=%g, want=%g", result.Value, tt.expected)
+		}
+	}
+
+	testIntegerObject(t, testEval("0x10 + 0b1 + 1_000"), 1017)
+	testBooleanObject(t, testEval("1 == 1.0"), true)
+	testBooleanObject(t, testEval("0.1 < 0.2"), true)
+
+	if inspected := testEval("6.0 / 2").Inspect(); inspected != "3.0" {
+		t.Errorf("Inspect() wrong. got=%q", inspected)
+	}
+}
+
+// 右辺は評価されないので、未定義の識別子でもエラーにならない
+func TestLogicalShortCircuit(t *testing.T) {
+	testBooleanObject(t, testEval("false && undefined"), false)
+	testBooleanObject(t, testEval("true || undefined"), true)
+
+	evaluated := testEval("true && undefined")
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "identifier not found: undefined" {
+		t.Errorf("expected error for evaluated right side. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(n) { for (let i = 0; i < 100; i = i + 1) { if (i == n) { return i * 10; } } return -1; }; f(7)", 70},
+		{"let f = fn() { for (let i = 0; i < 3; i = i + 1) { } return -1; }; f()", -1},
+		{"let f = fn(xs) { for (x in xs) { if (x > 2) { return x; } } return -1; }; f([1, 2, 3, 4])", 3},
+		{"let f = fn(xs) { for (x in xs) { if (x % 2 == 1) { continue; } return x; } }; f([1, 3, 4, 5])", 4},
+		{"let f = fn() { while (true) { break; } return 1; }; f()", 1},
+		{"let f = fn() { for (;;) { if (true) { break } } 2 }; f()", 2},
+		{"let f = fn() { for (x in [1, 2]) { for (y in [3, 4]) { break; } return x; } }; f()", 1},
+		{"let f = fn(s) { for (c in s) { if (c == \"b\") { return c; } } }; f(\"abc\")", "b"},
+		{"let f = fn(h) { for (k in h) { return k; } }; f({\"b\": 1, \"a\": 2})", "a"},
+		{"let i = 5; for (let i = 0; i < 1; i = i + 1) { } i", 5},
+		{"let sum = 0; for (let i = 1; i <= 4; i = i + 1) { sum = sum + i; } sum", 10},
+		{"let n = 0; while (n < 5) { n = n + 1; } n", 5},
+		{"let n = 10; while (n > 0) { n -= 3; } n", -2},
+		{"let i = 0; while (true) { let x = if (true) { break; }; i = i + 1; if (i > 3) { return 1; } } i", 0},
+		{"let i = 0; let n = 0; while (i < 3) { i += 1; n = n + len([if (true) { continue; }]); } n", 0},
+		{"let i = 0; let n = 0; while (i < 3) { i += 1; n = n + if (true) { continue; }; } n", 0},
+		{"let a = [0]; for (x in [1, 2]) { a[0] = if (x == 1) { continue; } else { x }; } a[0]", 2},
+		{"let i = 0; while (true) { i = if (i == 2) { break; } else { i + 1 }; } i", 2},
+		{"while (false) { }", nil},
+		{"for (x in []) { x }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("object is not String %q. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		case nil:
+			if evaluated != nil {
+				t.Errorf("loop statement has a value. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x += 2; x", 3},
+		{"let x = 10; x -= 4; x", 6},
+		{"let x = 3; x *= 4; x", 12},
+		{"let x = 12; x /= 4; x", 3},
+		{"let x = 1.5; x += 1; x", 2.5},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let x = 1; if (true) { x = 5; } x", 5},
+		{"let x = 1; if (true) { let x = 2; x = 3; } x", 1},
+		{"let x = 0; let f = fn() { x += 1; }; f(); f(); x", 2},
+		{"let sum = 0; for (let i = 1; i <= 4; i += 1) { sum += i; } sum", 10},
+		{"let i = 0; while (i < 5) { i += 1; } i", 5},
+		{"let a = [1, 2, 3]; a[1] = 20; a[1]", 20},
+		{"let a = [1, 2, 3]; a[2] *= 10; a[2]", 30},
+		{"let a = [1, 2]; let b = a; b[0] = 9; a[0]", 9},
+		{`let h = {"k": 1}; h["k"] += 1; h["k"]`, 2},
+		{`let h = {}; h["new"] = 5; h["new"]`, 5},
+		{"let m = [[1, 2], [3, 4]]; m[1][0] = 30; m[1][0]", 30},
+		{"let x = 1; x = 2", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			f, ok := evaluated.(*object.Float)
+			if !ok || f.Value != expected {
+				t.Errorf("object is not Float %g. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("object is not String %q. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		case nil:
+			if evaluated != nil {
+				t.Errorf("assignment has a value. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestElseIfExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (false) { 1 } else if (true) { 2 } else { 3 }", 2},
+		{"if (false) { 1 } else if (false) { 2 } else { 3 }", 3},
+		{"if (true) { 1 } else if (true) { 2 } else { 3 }", 1},
+		{"if (false) { 1 } else if (false) { 2 }", nil},
+		{"let f = fn(n) { if (n < 0) { -1 } else if (n == 0) { 0 } else if (n < 10) { 1 } else { 2 } }; f(5)", 1},
+		{"let f = fn(n) { if (n < 0) { return -1; } else if (n > 0) { return 1; } 0 }; f(-3)", -1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func testEval(input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+
+	return Eval(program, env)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	if obj != object.NULL {
+		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
+		return false
+	}
+	return true
+}
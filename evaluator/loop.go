@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"sort"
+)
+
+/*
+ループは文なので値を持たない（letと同じくnilを返す）
+本体はReturnValueとErrorならそのまま外へ返す、Breakで抜けてContinueで次へ
+*/
+
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		if result, stop := evalLoopBody(ws.Body, env); stop {
+			return result
+		}
+	}
+}
+
+// initで作った束縛はループの中だけで見える
+func evalForStatement(fs *ast.ForStatement, outer *object.Environment) object.Object {
+	env := object.NewEnclosedEnvironment(outer)
+
+	if fs.Init != nil {
+		if init := Eval(fs.Init, env); isAbrupt(init) {
+			return init
+		}
+	}
+
+	for {
+		if fs.Condition != nil {
+			condition := Eval(fs.Condition, env)
+			if isAbrupt(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return nil
+			}
+		}
+
+		if result, stop := evalLoopBody(fs.Body, env); stop {
+			return result
+		}
+
+		if fs.Post != nil {
+			if post := Eval(fs.Post, env); isAbrupt(post) {
+				return post
+			}
+		}
+	}
+}
+
+// 配列は要素、文字列は一文字ずつ、ハッシュはキーを順に束縛する
+func evalForInStatement(fi *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(fi.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+
+	var items []object.Object
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		items = iterable.Elements
+	case *object.String:
+		for _, r := range iterable.Value {
+			items = append(items, &object.String{Value: string(r)})
+		}
+	case *object.Hash:
+		items = hashKeys(iterable)
+	default:
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for _, item := range items {
+		iterEnv := object.NewEnclosedEnvironment(env)
+		iterEnv.Set(fi.Variable.Value, item)
+
+		if result, stop := evalLoopBody(fi.Body, iterEnv); stop {
+			return result
+		}
+	}
+	return nil
+}
+
+// ループを抜けるかどうかと、抜けるときの値（breakならnil）
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	result := Eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return nil, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	}
+	return nil, false
+}
+
+// mapの順番は決まらないので、Inspectと同じくキーの表示順にする
+func hashKeys(hash *object.Hash) []object.Object {
+	keys := make([]object.Object, 0, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		keys = append(keys, pair.Key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Inspect() < keys[j].Inspect() })
+	return keys
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"monkey/diff"
+	"monkey/format"
+	"os"
+	"path/filepath"
+)
+
+// monkey fmt [-l] [-w] [-d] [path ...]
+// パスがなければ標準入力を整えて標準出力に書く、ディレクトリは中の .monkey を全部整える
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	list := flags.Bool("l", false, "list files whose formatting differs from monkey fmt's")
+	write := flags.Bool("w", false, "write result to (source) file instead of stdout")
+	doDiff := flags.Bool("d", false, "display diffs instead of rewriting files")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: monkey fmt [flags] [path ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	f := &fmtCommand{list: *list, write: *write, diff: *doDiff, stdout: stdout, stderr: stderr}
+
+	if flags.NArg() == 0 {
+		if f.write {
+			fmt.Fprintln(stderr, "monkey fmt: cannot use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		f.process("<standard input>", src, 0)
+		return f.exitCode
+	}
+
+	for _, path := range flags.Args() {
+		f.processPath(path)
+	}
+	return f.exitCode
+}
+
+type fmtCommand struct {
+	list, write, diff bool
+	stdout, stderr    io.Writer
+	exitCode          int
+}
+
+func (f *fmtCommand) processPath(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		f.report(err)
+		return
+	}
+	if !info.IsDir() {
+		f.processFile(path, info.Mode().Perm())
+		return
+	}
+
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			f.report(err)
+			return nil
+		}
+		if d.IsDir() || filepath.Ext(p) != ".monkey" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			f.report(err)
+			return nil
+		}
+		f.processFile(p, info.Mode().Perm())
+		return nil
+	})
+	if err != nil {
+		f.report(err)
+	}
+}
+
+func (f *fmtCommand) processFile(path string, perm fs.FileMode) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		f.report(err)
+		return
+	}
+	f.process(path, src, perm)
+}
+
+func (f *fmtCommand) process(filename string, src []byte, perm fs.FileMode) {
+	res, err := format.Source(filename, src)
+	if err != nil {
+		if errs, ok := err.(format.ParseErrors); ok {
+			for _, e := range errs {
+				fmt.Fprintln(f.stderr, e.Render(string(src)))
+			}
+			f.exitCode = 1
+			return
+		}
+		f.report(err)
+		return
+	}
+
+	changed := !bytes.Equal(src, res)
+	if changed && f.list {
+		fmt.Fprintln(f.stdout, filename)
+	}
+	if changed && f.write {
+		if err := os.WriteFile(filename, res, perm); err != nil {
+			f.report(err)
+			return
+		}
+	}
+	if changed && f.diff {
+		f.stdout.Write(diff.Unified(filename+".orig", filename, src, res))
+	}
+	if !f.list && !f.write && !f.diff {
+		f.stdout.Write(res)
+	}
+}
+
+func (f *fmtCommand) report(err error) {
+	fmt.Fprintln(f.stderr, err)
+	f.exitCode = 1
+}
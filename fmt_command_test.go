@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	unformatted = "let x=1+2\n"
+	formatted   = "let x = 1 + 2;\n"
+)
+
+func TestFmtStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runFmt(nil, strings.NewReader(unformatted), &stdout, &stderr)
+
+	if code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d (stderr=%q)", code, stderr.String())
+	}
+	if stdout.String() != formatted {
+		t.Errorf("stdout wrong. expected=%q, got=%q", formatted, stdout.String())
+	}
+}
+
+// ディレクトリは中を全部たどり、.monkey 以外は触らない
+func TestFmtDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.monkey"), unformatted, 0644)
+	writeFile(t, filepath.Join(dir, "ok.monkey"), formatted, 0644)
+	writeFile(t, filepath.Join(dir, "sub", "b.monkey"), unformatted, 0644)
+	writeFile(t, filepath.Join(dir, "sub", "c.txt"), unformatted, 0644)
+
+	var stdout, stderr bytes.Buffer
+	if code := runFmt([]string{"-l", dir}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d (stderr=%q)", code, stderr.String())
+	}
+	expected := filepath.Join(dir, "a.monkey") + "\n" + filepath.Join(dir, "sub", "b.monkey") + "\n"
+	if stdout.String() != expected {
+		t.Errorf("-l output wrong. expected=%q, got=%q", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := runFmt([]string{"-w", dir}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d (stderr=%q)", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("-w should not write to stdout. got=%q", stdout.String())
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{filepath.Join(dir, "a.monkey"), formatted},
+		{filepath.Join(dir, "ok.monkey"), formatted},
+		{filepath.Join(dir, "sub", "b.monkey"), formatted},
+		{filepath.Join(dir, "sub", "c.txt"), unformatted},
+	}
+	for _, tt := range tests {
+		if got := readFile(t, tt.path); got != tt.expected {
+			t.Errorf("%s wrong. expected=%q, got=%q", tt.path, tt.expected, got)
+		}
+	}
+}
+
+func TestFmtWritePreservesMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.monkey")
+	writeFile(t, path, unformatted, 0600)
+
+	var stdout, stderr bytes.Buffer
+	if code := runFmt([]string{"-w", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d (stderr=%q)", code, stderr.String())
+	}
+
+	if got := readFile(t, path); got != formatted {
+		t.Errorf("file wrong. expected=%q, got=%q", formatted, got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode wrong. expected=%v, got=%v", os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestFmtDiff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.monkey")
+	writeFile(t, path, unformatted, 0644)
+
+	var stdout, stderr bytes.Buffer
+	if code := runFmt([]string{"-d", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code wrong. expected=0, got=%d (stderr=%q)", code, stderr.String())
+	}
+
+	expected := "--- " + path + ".orig\n+++ " + path + "\n@@ -1,1 +1,1 @@\n-let x=1+2\n+let x = 1 + 2;\n"
+	if stdout.String() != expected {
+		t.Errorf("-d output wrong. expected=%q, got=%q", expected, stdout.String())
+	}
+	if got := readFile(t, path); got != unformatted {
+		t.Errorf("-d should not change the file. got=%q", got)
+	}
+}
+
+func TestFmtErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.monkey")
+	writeFile(t, bad, "let = 1;\n", 0644)
+
+	tests := []struct {
+		args           []string
+		expectedCode   int
+		expectedStderr string
+	}{
+		{[]string{"-w"}, 2, "monkey fmt: cannot use -w with standard input"},
+		{[]string{"-x"}, 2, "flag provided but not defined: -x"},
+		{[]string{bad}, 1, bad + ":1:5: expected next token to be IDENT, got = instead [P001]"},
+		{[]string{"-w", bad}, 1, bad + ":1:5: expected next token to be IDENT, got = instead [P001]"},
+		{[]string{filepath.Join(dir, "missing.monkey")}, 1, "no such file or directory"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runFmt(tt.args, strings.NewReader(unformatted), &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("exit code wrong for %q. expected=%d, got=%d", tt.args, tt.expectedCode, code)
+		}
+		if !strings.Contains(stderr.String(), tt.expectedStderr) {
+			t.Errorf("stderr wrong for %q. expected to contain %q, got=%q", tt.args, tt.expectedStderr, stderr.String())
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("stdout should be empty for %q. got=%q", tt.args, stdout.String())
+		}
+	}
+
+	// 構文エラーのファイルは書き換えない
+	if got := readFile(t, bad); got != "let = 1;\n" {
+		t.Errorf("file with errors was changed. got=%q", got)
+	}
+}
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil { // umaskに左右されないように
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
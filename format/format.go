@@ -0,0 +1,59 @@
+// formatはMonkeyのソースを決まった形に整える（gofmtのようなもの）
+//
+//   - インデントはタブ、ブロックは必ず改行して書く
+//   - 括弧は優先順位と結合の向きから、必要なところだけに付ける
+//   - コメントはソース上の位置で文の間に戻す、同じ行の後ろにあったものは行末に付ける
+//   - 式の途中のコメントはその場所に残す、行コメントの後ろは改行して続ける
+//   - 文の間の空行は一行までにまとめて残す
+//
+// 整えた結果をもう一度整えても変わらない
+package format
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+)
+
+// 構文エラーがあると整えられない
+type ParseErrors []*parser.ParseError
+
+func (e ParseErrors) Error() string {
+	msgs := []string{}
+	for _, err := range e {
+		msgs = append(msgs, err.Pos.String()+": "+err.Msg)
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ソース全体を整える、filenameはエラーの位置に使う
+func Source(filename string, src []byte) ([]byte, error) {
+	input := string(src)
+
+	p := parser.New(lexer.NewFile(filename, input))
+	program := p.ParseProgram()
+	if len(p.ParseErrors()) != 0 {
+		return nil, ParseErrors(p.ParseErrors())
+	}
+
+	pr := newPrinter(input)
+	pr.program(program)
+	return pr.out.Bytes(), nil
+}
+
+// ノードを整えた文字列にする、ソースがないのでコメントと空行は出ない
+func Node(node ast.Node) string {
+	pr := newPrinter("")
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.program(node)
+	case *ast.BlockStatement:
+		pr.block(node)
+	case ast.Statement:
+		pr.statement(node)
+	case ast.Expression:
+		pr.expression(node)
+	}
+	return pr.out.String()
+}
@@ -0,0 +1,315 @@
+package format
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 括弧は要るところだけ
+		{"((a + b) * c)", "(a + b) * c;\n"},
+		{"a + (b * c)", "a + b * c;\n"},
+		{"(a - b) - c", "a - b - c;\n"},
+		{"a - (b - c)", "a - (b - c);\n"},
+		{"(a ** b) ** c", "(a ** b) ** c;\n"},
+		{"a ** (b ** c)", "a ** b ** c;\n"},
+		{"-(a ** b)", "-a ** b;\n"},
+		{"(-a) ** b", "(-a) ** b;\n"},
+		{"a ** (-b)", "a ** -b;\n"},
+		{"-(a + b)", "-(a + b);\n"},
+		{"!(-a)", "!-a;\n"},
+		{"(a && b) || (c && d)", "a && b || c && d;\n"},
+		{"a && (b || c)", "a && (b || c);\n"},
+		{"(a | b) & c", "(a | b) & c;\n"},
+		{"(a < b) == (c > d)", "a < b == c > d;\n"},
+		{"(f)(x)[0]", "f(x)[0];\n"},
+		{"(a + b)(c)", "(a + b)(c);\n"},
+		{"(a + b)[c + d]", "(a + b)[c + d];\n"},
+		{"f((a + b), [(1)], {(k): (v)})", "f(a + b, [1], {k: v});\n"},
+		{"0xFF + 1_000 + 1.5e3", "0xFF + 1_000 + 1.5e3;\n"},
+		{`"a\tb\u{1F600}"`, "\"a\\tb😀\";\n"},
+
+		// 文
+		{"let x = 1", "let x = 1;\n"},
+		{"return", "return;\n"},
+		{"x+=1", "x += 1;\n"},
+		{"a[i]=v", "a[i] = v;\n"},
+		{"let f = fn(a,b){a+b}", "let f = fn(a, b) {\n\ta + b;\n};\n"},
+		{"fn(){}", "fn() {};\n"},
+		{"let m = macro(x){quote(unquote(x))}", "let m = macro(x) {\n\tquote(unquote(x));\n};\n"},
+		{"if(x){1}", "if (x) {\n\t1;\n}\n"},
+		{"if(x){1}else{2}", "if (x) {\n\t1;\n} else {\n\t2;\n}\n"},
+		{"if(a){1}else if(b){2}else{3}", "if (a) {\n\t1;\n} else if (b) {\n\t2;\n} else {\n\t3;\n}\n"},
+		{"if(a){1}else{if(b){2}}", "if (a) {\n\t1;\n} else {\n\tif (b) {\n\t\t2;\n\t}\n}\n"},
+		{"let x = if(a){1}else{2}", "let x = if (a) {\n\t1;\n} else {\n\t2;\n};\n"},
+		{"while(x){break;continue}", "while (x) {\n\tbreak;\n\tcontinue;\n}\n"},
+		{"for(let i=0;i<n;i+=1){}", "for (let i = 0; i < n; i += 1) {}\n"},
+		{"for(;;){}", "for (;;) {}\n"},
+		{"for(let i=0;;){}", "for (let i = 0;;) {}\n"},
+		{"for(x in xs){f(x)}", "for (x in xs) {\n\tf(x);\n}\n"},
+		{"fn(){ if(a){ while(b){ c } } }", "fn() {\n\tif (a) {\n\t\twhile (b) {\n\t\t\tc;\n\t\t}\n\t}\n};\n"},
+
+		// ifの後ろに ( [ - で始まる文が来るときだけ ; が残る
+		{"if(a){1}; -1", "if (a) {\n\t1;\n};\n-1;\n"},
+		{"if(a){1}; (a + b) * c", "if (a) {\n\t1;\n};\n(a + b) * c;\n"},
+		{"if(a){1}; [1]", "if (a) {\n\t1;\n};\n[1];\n"},
+		{"if(a){1}; b", "if (a) {\n\t1;\n}\nb;\n"},
+
+		// 空行は一行にまとめて残す
+		{"a;\n\n\n\nb; c;\nd;", "a;\n\nb;\nc;\nd;\n"},
+		{"\n\nfn() {\n\n  a;\n\n  b;\n\n}", "fn() {\n\ta;\n\n\tb;\n};\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		out, err := Source("", []byte(tt.input))
+		if err != nil {
+			t.Fatalf("Source(%q) returned an error: %s", tt.input, err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("wrong result for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, out)
+		}
+		testFormatted(t, tt.input, string(out))
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"// head\nlet x = 1; // one\n/* block */ let y = 2;",
+			"// head\nlet x = 1; // one\n/* block */\nlet y = 2;\n",
+		},
+		{
+			"let f = fn() { // why\n  // first\n  a;\n\n  // last\n};",
+			"let f = fn() { // why\n\t// first\n\ta;\n\n\t// last\n};\n",
+		},
+		{
+			"if (a) { /* empty */ } else {\n// nothing\n}",
+			"if (a) { /* empty */\n} else {\n\t// nothing\n}\n",
+		},
+		{
+			"let a = [1, // one\n 2];\nb;",
+			"let a = [1, // one\n\t2];\nb;\n",
+		},
+		// 式の途中のコメントはその場所に残す、行コメントの後ろは改行して続ける
+		{
+			"let x = [\n  1, // one\n  /* two */ 2 // two\n];\n",
+			"let x = [1, // one\n\t/* two */ 2 // two\n];\n",
+		},
+		{
+			"let x = 1 +\n\t/* a */ 2; // b",
+			"let x = 1 + /* a */ 2; // b\n",
+		},
+		{
+			"let f = fn(x /* the x */, y) {\n  x\n};",
+			"let f = fn(x /* the x */, y) {\n\tx;\n};\n",
+		},
+		{
+			"f(1, /* arg */ 2)",
+			"f(1, /* arg */ 2);\n",
+		},
+		{
+			"let h = {\n  \"a\": 1, // first\n  \"b\" /* key */: 2\n};",
+			"let h = {\"a\": 1, // first\n\t\"b\" /* key */: 2};\n",
+		},
+		{
+			"if (a /* cond */) { b } /* else */ else { c }",
+			"if (a /* cond */) {\n\tb;\n} /* else */ else {\n\tc;\n}\n",
+		},
+		{
+			"let g = fn() {\n  return f(\n    // first\n    1,\n    2 /* last */\n  );\n};",
+			"let g = fn() {\n\treturn f( // first\n\t\t1, 2 /* last */);\n};\n",
+		},
+		{
+			"let a = 1;\n/* x */ // y\nlet b = a /* c */ * 2;",
+			"let a = 1;\n/* x */ // y\nlet b = a /* c */ * 2;\n",
+		},
+		{
+			"a;\n\n// detached\n\nb; // end\n\n// eof",
+			"a;\n\n// detached\n\nb; // end\n\n// eof\n",
+		},
+		{
+			"/* multi\n   line */\nx;",
+			"/* multi\n   line */\nx;\n",
+		},
+		{
+			"// only a comment",
+			"// only a comment\n",
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := Source("", []byte(tt.input))
+		if err != nil {
+			t.Fatalf("Source(%q) returned an error: %s", tt.input, err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("wrong result for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, out)
+		}
+		testFormatted(t, tt.input, string(out))
+	}
+}
+
+// どのトークンの前にコメントがあっても、木を変えず、二度目で変わらず、コメントも消えない
+func TestSourceCommentsEverywhere(t *testing.T) {
+	input := `let f = fn(x, y) { return g(x, [1, (2)][0], {"a": -y}); };
+if (x < y) { x } else if (y) { y = y + 1; } else { 0 }
+for (k in h) { while ((k)) { break; } }
+(1 + 2) * 3;`
+
+	var offsets []int
+	l := lexer.New(input)
+	for tok := l.NextToken(); ; tok = l.NextToken() {
+		offsets = append(offsets, tok.Pos.Offset)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	for _, offset := range offsets {
+		for _, c := range []string{"/* c */ ", "// c\n", "\n/* c */\n"} {
+			src := input[:offset] + c + input[offset:]
+			out, err := Source("", []byte(src))
+			if err != nil {
+				t.Fatalf("Source(%q) returned an error: %s", src, err)
+			}
+			testFormatted(t, src, string(out))
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	_, err := Source("bad.monkey", []byte("let x = ;\nlet = 1;"))
+	if err == nil {
+		t.Fatalf("no error for invalid source")
+	}
+
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatalf("err is not ParseErrors. got=%T", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("wrong number of errors. got=%d", len(errs))
+	}
+
+	expected := "bad.monkey:1:9: no prefix parse function for ; found\n" +
+		"bad.monkey:2:5: expected next token to be IDENT, got = instead"
+	if err.Error() != expected {
+		t.Errorf("wrong message.\nexpected=%q\ngot=     %q", expected, err.Error())
+	}
+}
+
+func TestNode(t *testing.T) {
+	p := parser.New(lexer.New("let f = fn(x) { if (x) { (1 + 2) * 3 } }"))
+	program := p.ParseProgram()
+
+	let := program.Statements[0].(*ast.LetStatement)
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, "let f = fn(x) {\n\tif (x) {\n\t\t(1 + 2) * 3;\n\t}\n};\n"},
+		{let, "let f = fn(x) {\n\tif (x) {\n\t\t(1 + 2) * 3;\n\t}\n};"},
+		{let.Value, "fn(x) {\n\tif (x) {\n\t\t(1 + 2) * 3;\n\t}\n}"},
+	}
+
+	for _, tt := range tests {
+		if got := Node(tt.node); got != tt.expected {
+			t.Errorf("wrong result for %T.\nexpected=%q\ngot=     %q", tt.node, tt.expected, got)
+		}
+	}
+}
+
+// unquoteで作られたリテラルはソースにないので、値から書いて同じ値に読み直せるようにする
+func TestNodeMacroExpanded(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"quote(unquote(0 - 1) ** 2)", "(-1) ** 2"},
+		{"quote(2 ** unquote(0 - 1))", "2 ** -1"},
+		{"quote(unquote(0 - 1)[0])", "(-1)[0]"},
+		{"quote(-unquote(0 - 1))", "--1"},
+		{"quote(unquote(0.5 - 1.0) * 2)", "-0.5 * 2"},
+		{"quote(unquote(2.0) + 1)", "2.0 + 1"},
+		{"quote(unquote(1.0 / 0) + 1)", "1.0 / 0.0 + 1"},
+		{"quote(2 * unquote(-1.0 / 0))", "2 * (-1.0 / 0.0)"},
+		{"quote(unquote(0.0 / 0))", "0.0 / 0.0"},
+		{"quote(unquote(-9223372036854775807 - 1) * 2)", "(-9223372036854775807 - 1) * 2"},
+	}
+
+	for _, tt := range tests {
+		input := "let m = macro() { " + tt.input + " }; m();"
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser has errors for %q: %q", input, p.Errors())
+		}
+
+		env := object.NewEnvironment()
+		evaluator.DefineMacros(program, env)
+		expanded, err := evaluator.ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("expansion failed for %q: %s", input, err.Inspect())
+		}
+
+		got := Node(expanded.(*ast.Program).Statements[0].(*ast.ExpressionStatement).Expression)
+		if got != tt.expected {
+			t.Errorf("wrong result for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+			continue
+		}
+
+		// 整えたソースを評価しても同じ値になる
+		want := evaluator.Eval(expanded, object.NewEnvironment()).Inspect()
+		formatted := parser.New(lexer.New(got)).ParseProgram()
+		if value := evaluator.Eval(formatted, object.NewEnvironment()).Inspect(); value != want {
+			t.Errorf("formatted %q evaluates to %s, want %s", got, value, want)
+		}
+	}
+}
+
+// 整えた結果は元と同じ木になり、もう一度整えても変わらない
+func testFormatted(t *testing.T, input, formatted string) {
+	t.Helper()
+
+	if parseString(t, formatted) != parseString(t, input) {
+		t.Errorf("formatting changed the program.\ninput=    %q\nformatted=%q", input, formatted)
+	}
+
+	again, err := Source("", []byte(formatted))
+	if err != nil {
+		t.Fatalf("formatted source does not parse: %s", err)
+	}
+	if string(again) != formatted {
+		t.Errorf("not idempotent.\nfirst= %q\nsecond=%q", formatted, again)
+	}
+
+	// コメントはなくならない
+	if strings.Count(formatted, "//") != strings.Count(input, "//") ||
+		strings.Count(formatted, "/*") != strings.Count(input, "/*") {
+		t.Errorf("comments were lost.\ninput=    %q\nformatted=%q", input, formatted)
+	}
+}
+
+func parseString(t *testing.T, input string) string {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has errors for %q: %q", input, p.Errors())
+	}
+	return program.String()
+}
@@ -0,0 +1,689 @@
+package format
+
+import (
+	"bytes"
+	"math"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type comment struct {
+	token.Comment
+	trailing bool // 前のトークンと同じ行にある
+}
+
+type printer struct {
+	out    bytes.Buffer
+	indent int
+
+	glue      bool // 最後の行がトークンで終わっている（行末のコメントを付けられる）
+	glueLine  int  // 最後の行がコメントだけなら、そのコメントが終わるソースの行
+	lineStart bool // 式の途中の行コメントで改行したところ（インデントはまだ書いていない）
+	spaceNext bool // 式の途中のブロックコメントの後ろ
+
+	// ソースがあるときだけ使う
+	src      string
+	comments []comment     // 位置順、まだ出していないもの
+	tokens   []token.Token // 位置順、コメントの前後のトークンを探すのに使う
+	closers  map[int]int   // ( [ { のオフセット -> 対応する閉じ括弧のオフセット
+}
+
+/*
+コメントはASTに入らないトークン（; や } など）にも付いているので、
+ソースを字句解析し直して全部集めておく
+*/
+func newPrinter(src string) *printer {
+	p := &printer{src: src, closers: map[int]int{}}
+	if src == "" {
+		return p
+	}
+
+	l := lexer.New(src)
+	opens := []int{}
+	for {
+		tok := l.NextToken()
+		for _, c := range tok.Leading {
+			p.comments = append(p.comments, comment{Comment: c})
+		}
+		for _, c := range tok.Trailing {
+			p.comments = append(p.comments, comment{Comment: c, trailing: true})
+		}
+
+		p.tokens = append(p.tokens, tok)
+		switch tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			opens = append(opens, tok.Pos.Offset)
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			if len(opens) > 0 {
+				p.closers[opens[len(opens)-1]] = tok.Pos.Offset
+				opens = opens[:len(opens)-1]
+			}
+		}
+
+		if tok.Type == token.EOF {
+			return p
+		}
+	}
+}
+
+func (p *printer) write(s string) {
+	if p.lineStart { // 続きの行は一段下げる、閉じ括弧とelseは元の深さ
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return
+		}
+		indent := p.indent + 1
+		if strings.ContainsRune(")]}", rune(s[0])) || strings.HasPrefix(s, "else") {
+			indent = p.indent
+		}
+		p.out.WriteString(strings.Repeat("\t", indent))
+		p.lineStart = false
+	}
+	if p.spaceNext {
+		if s != "" && !strings.ContainsRune(" \n,;:)]}", rune(s[0])) {
+			s = " " + s
+		}
+		p.spaceNext = false
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) writeIndent() {
+	p.write(strings.Repeat("\t", p.indent))
+}
+
+func (p *printer) program(program *ast.Program) {
+	first := true
+	p.statements(program.Statements, &first)
+	p.flushComments(len(p.src)+1, &first)
+}
+
+/*
+文を一行ずつ書く
+firstはまだ何も書いていないか（先頭には空行を入れない）
+*/
+func (p *printer) statements(list []ast.Statement, first *bool) {
+	semicolon := -1 // ; を後から入れるかもしれない位置
+
+	for _, stmt := range list {
+		start := firstToken(stmt).Pos
+		if start.IsValid() {
+			p.flushComments(start.Offset, first)
+		}
+		if es, ok := stmt.(*ast.ExpressionStatement); ok { // ( の中で式の前にあるコメントも文の前に書く
+			if head := firstToken(es.Expression).Pos; head.IsValid() {
+				p.flushComments(head.Offset, first)
+			}
+		}
+		if !*first && start.IsValid() && p.blankLineBefore(start.Offset) {
+			p.write("\n")
+		}
+
+		p.writeIndent()
+		written := p.out.Len()
+		p.statement(stmt)
+		if semicolon >= 0 && continuesExpression(p.out.Bytes()[written:]) {
+			p.insert(semicolon, ";")
+		}
+
+		semicolon = -1
+		if es, ok := stmt.(*ast.ExpressionStatement); ok {
+			if _, ok := es.Expression.(*ast.IfExpression); ok {
+				semicolon = p.out.Len()
+			}
+		}
+		p.write("\n")
+		p.glue, p.glueLine = true, 0
+		*first = false
+	}
+}
+
+/*
+if (...) { } の後ろに ( [ - で始まる文が来ると、続けて呼び出し・添字・引き算として読まれる
+そのときだけifの後ろを ; で区切る
+*/
+func continuesExpression(stmt []byte) bool {
+	return len(stmt) > 0 && (stmt[0] == '(' || stmt[0] == '[' || stmt[0] == '-')
+}
+
+func (p *printer) insert(pos int, s string) {
+	rest := append([]byte(s), p.out.Bytes()[pos:]...)
+	p.out.Truncate(pos)
+	p.out.Write(rest)
+}
+
+/*
+offsetより前にあるコメントを文の間に書く
+同じ行の後ろにあったコメントは直前の行の末尾に付ける
+ただし直前の行がコメントだけの行なら付けない
+*/
+func (p *printer) flushComments(offset int, first *bool) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if (c.trailing && p.glue) || c.Pos.Line == p.glueLine {
+			p.out.Truncate(p.out.Len() - 1) // 改行を取って後ろに付ける
+			p.write(" " + c.Text + "\n")
+			continue
+		}
+
+		if !*first && p.blankLineBefore(c.Pos.Offset) {
+			p.write("\n")
+		}
+		p.writeIndent()
+		p.write(c.Text + "\n")
+		p.glue = false
+		p.glueLine = c.Pos.Line + strings.Count(c.Text, "\n")
+		*first = false
+	}
+}
+
+/*
+式の途中にあるコメントを、offsetのトークンの手前に書く
+ブロックコメントはその場に、行コメントは後ろで改行して続ける
+*/
+func (p *printer) inline(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		lineComment := strings.HasPrefix(c.Text, "//")
+		noSpace := " \t\n([{"
+		if lineComment {
+			noSpace = " \t\n"
+		}
+		if b := p.out.Bytes(); len(b) > 0 && !strings.ContainsRune(noSpace, rune(b[len(b)-1])) {
+			p.out.WriteByte(' ')
+		}
+		p.spaceNext = false
+		p.write(c.Text)
+
+		if lineComment {
+			p.out.WriteString("\n")
+			p.lineStart = true
+		} else {
+			p.spaceNext = true
+		}
+	}
+}
+
+/*
+ソースでoffsetの直前にあるトークンの位置（, : = ) などを探すのに使う）
+式を囲む ( は飛ばす ex) f(1, (2)) の2からは , を返す
+*/
+func (p *printer) before(offset int) int {
+	i := sort.Search(len(p.tokens), func(i int) bool { return p.tokens[i].Pos.Offset >= offset })
+	if offset < 0 {
+		return -1
+	}
+	for i > 0 && p.tokens[i-1].Type == token.LPAREN {
+		i--
+	}
+	if i == 0 {
+		return -1
+	}
+	return p.tokens[i-1].Pos.Offset
+}
+
+// ソースでoffsetの直後にあるトークンの位置
+func (p *printer) after(offset int) int {
+	i := sort.Search(len(p.tokens), func(i int) bool { return p.tokens[i].Pos.Offset > offset })
+	if offset < 0 || i == len(p.tokens) {
+		return -1
+	}
+	return p.tokens[i].Pos.Offset
+}
+
+// 対応する閉じ括弧の位置
+func (p *printer) closer(open int) int {
+	if close, ok := p.closers[open]; ok {
+		return close
+	}
+	return -1
+}
+
+// ノードの先頭のトークンの位置、ソースのない木では-1
+func (p *printer) start(node ast.Node) int {
+	return p.tokenOffset(firstToken(node))
+}
+
+// offsetの行の前が空行か
+func (p *printer) blankLineBefore(offset int) bool {
+	if p.src == "" || offset > len(p.src) {
+		return false
+	}
+
+	i := offset - 1
+	newlines := 0
+	for ; i >= 0; i-- {
+		switch p.src[i] {
+		case ' ', '\t', '\r':
+		case '\n':
+			newlines++
+			if newlines == 2 {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// ifの式文には ; を付けない（要るときはstatementsで足す）
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.let(stmt)
+		p.write(";")
+	case *ast.ReturnStatement:
+		p.write("return")
+		if stmt.Value != nil {
+			p.write(" ")
+			p.expression(stmt.Value)
+		}
+		p.write(";")
+	case *ast.AssignStatement:
+		p.simpleStatement(stmt)
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+		if _, ok := stmt.Expression.(*ast.IfExpression); !ok {
+			p.write(";")
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	case *ast.WhileStatement:
+		p.write("while (")
+		p.expression(stmt.Condition)
+		p.inline(p.before(p.start(stmt.Body))) // )
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.ForStatement:
+		p.write("for (")
+		if stmt.Init != nil {
+			p.simpleStatement(stmt.Init)
+		}
+		p.write(";")
+		if stmt.Condition != nil {
+			p.write(" ")
+			p.expression(stmt.Condition)
+		}
+		p.write(";")
+		if stmt.Post != nil {
+			p.write(" ")
+			p.simpleStatement(stmt.Post)
+		}
+		p.inline(p.before(p.start(stmt.Body))) // )
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.ForInStatement:
+		p.write("for (")
+		p.inline(p.start(stmt.Variable))
+		p.write(stmt.Variable.Value)
+		p.inline(p.before(p.start(stmt.Iterable))) // in
+		p.write(" in ")
+		p.expression(stmt.Iterable)
+		p.inline(p.before(p.start(stmt.Body))) // )
+		p.write(") ")
+		p.block(stmt.Body)
+	case *ast.BreakStatement:
+		p.write("break;")
+	case *ast.ContinueStatement:
+		p.write("continue;")
+	}
+}
+
+// forの初期化と更新、; を付けない
+func (p *printer) simpleStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.let(stmt)
+	case *ast.AssignStatement:
+		p.expression(stmt.Target)
+		p.inline(p.tokenOffset(stmt.Token))
+		p.write(" " + stmt.Operator + " ")
+		p.expression(stmt.Value)
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+	}
+}
+
+func (p *printer) let(stmt *ast.LetStatement) {
+	p.write("let ")
+	p.inline(p.start(stmt.Name))
+	p.write(stmt.Name.Value)
+	p.inline(p.before(p.start(stmt.Value))) // =
+	p.write(" = ")
+	p.expression(stmt.Value)
+}
+
+// 中身がなくコメントもなければ {}
+func (p *printer) block(block *ast.BlockStatement) {
+	end := -1
+	if p.src != "" && block.Token.Type == token.LBRACE {
+		p.inline(block.Token.Pos.Offset)
+		end = p.closer(block.Token.Pos.Offset)
+	}
+
+	hasComments := len(p.comments) > 0 && p.comments[0].Pos.Offset < end
+	if len(block.Statements) == 0 && !hasComments {
+		p.write("{}")
+		return
+	}
+
+	p.write("{\n")
+	p.glue, p.glueLine = true, 0
+	p.indent++
+	first := true
+	p.statements(block.Statements, &first)
+	p.flushComments(end, &first)
+	p.indent--
+	p.writeIndent()
+	p.write("}")
+}
+
+func (p *printer) expression(exp ast.Expression) {
+	p.inline(p.start(exp))
+
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.write(exp.Value)
+	case *ast.IntegerLiteral:
+		text, _ := integerLiteral(exp)
+		p.write(text)
+	case *ast.FloatLiteral:
+		text, _ := floatLiteral(exp)
+		p.write(text)
+	case *ast.StringLiteral:
+		p.write(ast.QuoteString(exp.Value))
+	case *ast.Boolean:
+		if exp.Value {
+			p.write("true")
+		} else {
+			p.write("false")
+		}
+	case *ast.PrefixExpression:
+		p.write(exp.Operator)
+		p.operand(exp.Right, precedenceOf(exp.Right) < token.PREFIX)
+	case *ast.InfixExpression:
+		p.binary(exp.Left, exp.Token, exp.Right)
+	case *ast.LogicalExpression:
+		p.binary(exp.Left, exp.Token, exp.Right)
+	case *ast.IfExpression:
+		p.ifExpression(exp)
+	case *ast.FunctionLiteral:
+		p.write("fn")
+		p.parameters(p.after(p.tokenOffset(exp.Token)), exp.Parameters)
+		p.write(" ")
+		p.block(exp.Body)
+	case *ast.MacroLiteral:
+		p.write("macro")
+		p.parameters(p.after(p.tokenOffset(exp.Token)), exp.Parameters)
+		p.write(" ")
+		p.block(exp.Body)
+	case *ast.CallExpression:
+		p.operand(exp.Function, precedenceOf(exp.Function) < token.CALL)
+		p.inline(p.tokenOffset(exp.Token))
+		p.write("(")
+		p.expressionList(exp.Arguments)
+		p.inline(p.closer(p.tokenOffset(exp.Token)))
+		p.write(")")
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.expressionList(exp.Elements)
+		p.inline(p.closer(p.tokenOffset(exp.Token)))
+		p.write("]")
+	case *ast.IndexExpression:
+		p.operand(exp.Left, precedenceOf(exp.Left) < token.CALL) // f(x)[0] や a[0](x) は続けて書ける
+		p.inline(p.tokenOffset(exp.Token))
+		p.write("[")
+		p.expression(exp.Index)
+		p.inline(p.closer(p.tokenOffset(exp.Token)))
+		p.write("]")
+	case *ast.HashLiteral:
+		p.write("{")
+		for i, pair := range exp.Pairs {
+			if i > 0 {
+				p.inline(p.before(p.start(pair.Key))) // ,
+				p.write(", ")
+			}
+			p.expression(pair.Key)
+			p.inline(p.before(p.start(pair.Value))) // :
+			p.write(": ")
+			p.expression(pair.Value)
+		}
+		p.inline(p.closer(p.tokenOffset(exp.Token)))
+		p.write("}")
+	}
+}
+
+/*
+左右の式の優先順位が演算子より低ければ括弧を付ける
+同じなら、左結合の演算子は右に、右結合の演算子は左に付ける
+右の式が前置演算子（負の数も）なら、演算子の後ろからしか始まらないので付けない ex) 2 ** -1
+*/
+func (p *printer) binary(left ast.Expression, op token.Token, right ast.Expression) {
+	operator := op.Literal
+	precedence := parser.Precedence(token.TokenType(operator))
+	rightAssociative := parser.IsRightAssociative(token.TokenType(operator))
+
+	leftPrecedence := precedenceOf(left)
+	p.operand(left, leftPrecedence < precedence || (leftPrecedence == precedence && rightAssociative))
+
+	p.inline(p.tokenOffset(op))
+	p.write(" " + operator + " ")
+
+	rightPrecedence := precedenceOf(right)
+	if rightPrecedence == token.PREFIX {
+		p.expression(right)
+		return
+	}
+	p.operand(right, rightPrecedence < precedence || (rightPrecedence == precedence && !rightAssociative))
+}
+
+func (p *printer) operand(exp ast.Expression, parens bool) {
+	if parens {
+		p.write("(")
+		p.expression(exp)
+		p.write(")")
+		return
+	}
+	p.expression(exp)
+}
+
+// else if はブロックで包まずに続けて書く
+func (p *printer) ifExpression(ie *ast.IfExpression) {
+	p.inline(p.tokenOffset(ie.Token))
+	p.write("if (")
+	p.expression(ie.Condition)
+	p.inline(p.before(p.start(ie.Consequence))) // )
+	p.write(") ")
+	p.block(ie.Consequence)
+
+	if ie.Alternative == nil {
+		return
+	}
+	p.inline(p.after(p.closer(p.start(ie.Consequence)))) // else
+	p.write(" else ")
+	if elseIf := ie.ElseIf(); elseIf != nil {
+		p.ifExpression(elseIf)
+	} else {
+		p.block(ie.Alternative)
+	}
+}
+
+// lparenは ( の位置
+func (p *printer) parameters(lparen int, params []*ast.Identifier) {
+	p.inline(lparen)
+	p.write("(")
+	for i, param := range params {
+		if i > 0 {
+			p.inline(p.before(p.start(param))) // ,
+			p.write(", ")
+		}
+		p.inline(p.start(param))
+		p.write(param.Value)
+	}
+	p.inline(p.closer(lparen))
+	p.write(")")
+}
+
+func (p *printer) expressionList(list []ast.Expression) {
+	for i, exp := range list {
+		if i > 0 {
+			p.inline(p.before(p.start(exp))) // ,
+			p.write(", ")
+		}
+		p.expression(exp)
+	}
+}
+
+// トークンの位置、ソースのない木では-1
+func (p *printer) tokenOffset(tok token.Token) int {
+	if p.src == "" || !tok.Pos.IsValid() {
+		return -1
+	}
+	return tok.Pos.Offset
+}
+
+// 式の結びつきの強さ、リテラルや括弧で閉じている式は一番強い
+func precedenceOf(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(exp.Operator))
+	case *ast.LogicalExpression:
+		return parser.Precedence(token.TokenType(exp.Operator))
+	case *ast.PrefixExpression:
+		return token.PREFIX
+	case *ast.CallExpression:
+		return token.CALL
+	case *ast.IndexExpression:
+		return token.INDEX
+	case *ast.IntegerLiteral:
+		_, precedence := integerLiteral(exp)
+		return precedence
+	case *ast.FloatLiteral:
+		_, precedence := floatLiteral(exp)
+		return precedence
+	default:
+		return token.INDEX + 1
+	}
+}
+
+/*
+数値リテラルの書き方とその結びつきの強さ
+パーサーが作ったものはソースのまま書く（0xFF や 1_000）
+unquoteなどで作られたものは値から書く、負の数は前置の - と同じ強さになる
+*/
+func integerLiteral(lit *ast.IntegerLiteral) (string, int) {
+	if isSourceLiteral(lit.Token, token.INT) {
+		if v, err := strconv.ParseInt(lit.Token.Literal, 0, 64); err == nil && v == lit.Value {
+			return lit.Token.Literal, token.INDEX + 1
+		}
+	}
+
+	switch {
+	case lit.Value == math.MinInt64: // -9223372036854775808 は読み直すと桁あふれになる
+		return "-9223372036854775807 - 1", token.SUM
+	case lit.Value < 0:
+		return strconv.FormatInt(lit.Value, 10), token.PREFIX
+	default:
+		return strconv.FormatInt(lit.Value, 10), token.INDEX + 1
+	}
+}
+
+// InfとNaNはリテラルで書けないので、同じ値になる割り算にする
+func floatLiteral(lit *ast.FloatLiteral) (string, int) {
+	if isSourceLiteral(lit.Token, token.FLOAT) {
+		if v, err := strconv.ParseFloat(lit.Token.Literal, 64); err == nil && v == lit.Value {
+			return lit.Token.Literal, token.INDEX + 1
+		}
+	}
+
+	switch {
+	case math.IsInf(lit.Value, 1):
+		return "1.0 / 0.0", token.PRODUCT
+	case math.IsInf(lit.Value, -1):
+		return "-1.0 / 0.0", token.PRODUCT
+	case math.IsNaN(lit.Value):
+		return "0.0 / 0.0", token.PRODUCT
+	}
+
+	text := strconv.FormatFloat(lit.Value, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".e") { // 整数に見えないように
+		text += ".0"
+	}
+	if math.Signbit(lit.Value) {
+		return text, token.PREFIX
+	}
+	return text, token.INDEX + 1
+}
+
+// 字句解析で作られたリテラルか、数字で始まるので符号やInfは入らない
+func isSourceLiteral(tok token.Token, tt token.TokenType) bool {
+	return tok.Type == tt && tok.Literal != "" && tok.Literal[0] >= '0' && tok.Literal[0] <= '9'
+}
+
+// 文の先頭のトークン、代入は左辺の先頭になる
+func firstToken(node ast.Node) token.Token {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return node.Token
+	case *ast.ReturnStatement:
+		return node.Token
+	case *ast.AssignStatement:
+		return firstToken(node.Target)
+	case *ast.ExpressionStatement:
+		return node.Token // ( で始まる式でも ( を指している
+	case *ast.BlockStatement:
+		return node.Token
+	case *ast.WhileStatement:
+		return node.Token
+	case *ast.ForStatement:
+		return node.Token
+	case *ast.ForInStatement:
+		return node.Token
+	case *ast.BreakStatement:
+		return node.Token
+	case *ast.ContinueStatement:
+		return node.Token
+	case *ast.InfixExpression:
+		return firstToken(node.Left)
+	case *ast.LogicalExpression:
+		return firstToken(node.Left)
+	case *ast.CallExpression:
+		return firstToken(node.Function)
+	case *ast.IndexExpression:
+		return firstToken(node.Left)
+	case *ast.Identifier:
+		return node.Token
+	case *ast.IntegerLiteral:
+		return node.Token
+	case *ast.FloatLiteral:
+		return node.Token
+	case *ast.StringLiteral:
+		return node.Token
+	case *ast.Boolean:
+		return node.Token
+	case *ast.PrefixExpression:
+		return node.Token
+	case *ast.IfExpression:
+		return node.Token
+	case *ast.FunctionLiteral:
+		return node.Token
+	case *ast.MacroLiteral:
+		return node.Token
+	case *ast.ArrayLiteral:
+		return node.Token
+	case *ast.HashLiteral:
+		return node.Token
+	}
+	return token.Token{}
+}
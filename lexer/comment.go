@@ -0,0 +1,82 @@
+package lexer
+
+import (
+	"monkey/token"
+	"strings"
+)
+
+// コメントは // から行末までと /* から */ まで
+// 構文解析では使わないが、フォーマッタなどのためにトークンに残しておく
+
+// 空白とコメントを読み飛ばして、読んだコメントを返す
+// ブロックコメントが閉じていなければILLEGALトークンを返す
+func (l *Lexer) skipWhiteSpaceAndComments() ([]token.Comment, *token.Token) {
+	var comments []token.Comment
+	for {
+		l.skipWhiteSpace()
+
+		switch {
+		case l.character == '/' && l.peekChar() == '/':
+			comments = append(comments, l.readLineComment())
+		case l.character == '/' && l.peekChar() == '*':
+			pos := l.currentPos()
+			if !l.blockCommentClosed() {
+				l.errors[pos.Offset] = Error{ Pos: pos, Msg: "unterminated block comment" }
+				literal := l.input[pos.Offset:]
+				for l.character != 0 {
+					l.readChar()
+				}
+				return comments, &token.Token{ Type: token.ILLEGAL, Literal: literal, Pos: pos }
+			}
+			comments = append(comments, l.readBlockComment())
+		default:
+			return comments, nil
+		}
+	}
+}
+
+// トークンと同じ行にあるコメント、改行の手前で止まる
+func (l *Lexer) readTrailingComments() []token.Comment {
+	var comments []token.Comment
+	for {
+		for l.character == ' ' || l.character == '\t' {
+			l.readChar()
+		}
+
+		switch {
+		case l.character == '/' && l.peekChar() == '/':
+			comments = append(comments, l.readLineComment())
+		case l.character == '/' && l.peekChar() == '*' && l.blockCommentClosed():
+			comments = append(comments, l.readBlockComment())
+		default:
+			return comments
+		}
+	}
+}
+
+// 改行は含めない
+func (l *Lexer) readLineComment() token.Comment {
+	pos := l.currentPos()
+	for l.character != '\n' && l.character != 0 {
+		l.readChar()
+	}
+	text := strings.TrimRight(l.input[pos.Offset:l.position], "\r")
+	return token.Comment{ Text: text, Pos: pos }
+}
+
+// 閉じていることを確かめてから呼ぶ
+func (l *Lexer) readBlockComment() token.Comment {
+	pos := l.currentPos()
+	l.readChar() // /
+	l.readChar() // *
+	for !(l.character == '*' && l.peekChar() == '/') {
+		l.readChar()
+	}
+	l.readChar() // *
+	l.readChar() // /
+	return token.Comment{ Text: l.input[pos.Offset:l.position], Pos: pos }
+}
+
+func (l *Lexer) blockCommentClosed() bool {
+	return strings.Contains(l.input[l.position+2:], "*/")
+}
@@ -1,52 +1,121 @@
 package lexer
 
 import (
+	"fmt"
 	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 /*
 lexerは入力を先頭から読み込む
 入力、今見ている文字、今見ている文字の位置（次に何が来るかを見る必要がある）、次の文字の位置
+入力はUTF-8として一文字（rune）ずつ読む、位置はバイト単位
 */
 type Lexer struct {
 	input string
-	character byte
+	character rune
 	position int
 	nextPosition int
+	filename string
+	line int // 今見ている文字の行
+	column int // 今見ている文字の列（バイトではなく文字単位）
+	errors map[int]Error // ILLEGALトークンの先頭のオフセット -> エラー
+}
+
+// 字句解析のエラー、ILLEGALトークンに対応する
+type Error struct {
+	Pos token.Pos // 問題の箇所（トークンの先頭とは限らない）
+	Msg string
+}
+
+// ILLEGALトークンがなぜ不正なのか
+func (l *Lexer) ErrorFor(tok token.Token) (Error, bool) {
+	err, ok := l.errors[tok.Pos.Offset]
+	return err, ok && tok.Type == token.ILLEGAL
+}
+
+func (l *Lexer) addError(tokenPos token.Pos, pos token.Pos, format string, a ...interface{}) {
+	if _, ok := l.errors[tokenPos.Offset]; ok { // 一つのトークンにつき最初のエラーだけ
+		return
+	}
+	l.errors[tokenPos.Offset] = Error{ Pos: pos, Msg: fmt.Sprintf(format, a...) }
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// ファイル名つき、トークンの位置にファイル名が入る
+func NewFile(filename string, input string) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1, errors: map[int]Error{}}
 	l.readChar()
 	return l
 }
 
 // 次の一文字を読んでinput文字列の現在位置を進める
 func (l *Lexer) readChar() {
+	if l.nextPosition > len(l.input) { // EOFより先には進まない
+		return
+	}
+
+	// 行と列の更新、改行を読み終えたら次の行へ
+	if l.character == '\n' {
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
+
+	width := 1
 	if l.nextPosition >= len(l.input) {
 		l.character = 0 // NULに対応
 	} else {
-		l.character = l.input[l.nextPosition] // 次の文字
+		l.character, width = utf8.DecodeRuneInString(l.input[l.nextPosition:]) // 次の文字
 	}
 
 	// positionの更新
 	l.position = l.nextPosition
-	l.nextPosition += 1
+	l.nextPosition += width
 }
 
 //先読み
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.nextPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.nextPosition]
+		r, _ := utf8.DecodeRuneInString(l.input[l.nextPosition:])
+		return r
 	}
 }
 
-// l.characterを見てその文字に対応したトークンを返す
+// 今見ている文字の位置
+func (l *Lexer) currentPos() token.Pos {
+	return token.Pos{ Filename: l.filename, Line: l.line, Column: l.column, Offset: l.position }
+}
+
+// 次のトークンを返す、コメントは前後のトークンにくっつける
 func (l *Lexer) NextToken() token.Token {
+	leading, illegal := l.skipWhiteSpaceAndComments()
+	if illegal != nil { // 閉じていないブロックコメント
+		illegal.Leading = leading
+		return *illegal
+	}
+
+	tok := l.readToken()
+	tok.Leading = leading
+	if tok.Type != token.EOF {
+		tok.Trailing = l.readTrailingComments()
+	}
+	return tok
+}
+
+// l.characterを見てその文字に対応したトークンを返す
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
-	l.skipWhiteSpace()
+	pos := l.currentPos()
 	switch l.character {
 	case '=':
 		if l.peekChar() == '=' { // 次のトークンを覗き見
@@ -58,13 +127,37 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.character)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.character)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.character)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.character)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.character)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.character)
+		if l.peekChar() == '*' {
+			tok = l.readTwoCharToken(token.POW)
+		} else if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.ASTERISK_ASSIGN)
+		} else {
+			tok = newToken(token.ASTERISK, l.character)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.character)
+	case '^':
+		tok = newToken(token.BIT_XOR, l.character)
+	case '~':
+		tok = newToken(token.TILDE, l.character)
 	case '/':
-		tok = newToken(token.SLASH, l.character)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, l.character)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.character
@@ -75,9 +168,33 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.character)
 		}
 	case '<':
-		tok = newToken(token.LT, l.character)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.LT_EQ)
+		} else if l.peekChar() == '<' {
+			tok = l.readTwoCharToken(token.SHL)
+		} else {
+			tok = newToken(token.LT, l.character)
+		}
 	case '>':
-		tok = newToken(token.GT, l.character)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.GT_EQ)
+		} else if l.peekChar() == '>' {
+			tok = l.readTwoCharToken(token.SHR)
+		} else {
+			tok = newToken(token.GT, l.character)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.readTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.BIT_AND, l.character)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.readTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.BIT_OR, l.character)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.character)
 	case ')':
@@ -86,10 +203,22 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.character)
 	case '}':
 		tok = newToken(token.RBRACE, l.character)
+	case '[':
+		tok = newToken(token.LBRACKET, l.character)
+	case ']':
+		tok = newToken(token.RBRACKET, l.character)
 	case ',':
 		tok = newToken(token.COMMA, l.character)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.character)
+	case ':':
+		tok = newToken(token.COLON, l.character)
+	case '"':
+		if str, ok := l.readString(pos); ok {
+			tok = token.Token{ Type: token.STRING, Literal: str }
+		} else {
+			tok = token.Token{ Type: token.ILLEGAL, Literal: str }
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -97,50 +226,201 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.character) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.character){
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos = pos
 			return tok
 		}else {
 			tok = newToken(token.ILLEGAL, l.character)
+			l.addError(pos, pos, "illegal character %q", l.character)
 		}
 	}
+	tok.Pos = pos
 	l.readChar()
 	return tok
 }
 
 // 指定のトークンタイプでその文字をトークン化する
-func newToken(tokenType token.TokenType, character byte) token.Token {
+func newToken(tokenType token.TokenType, character rune) token.Token {
 	return token.Token{ Type: tokenType, Literal: string(character)}
 }
 
+// 今の文字と次の文字で一つのトークン ex) <= &&
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.character
+	l.readChar()
+	literal := string(ch) + string(l.character)
+	return token.Token{ Type: tokenType, Literal: literal }
+}
+
+// 先頭以外には数字も使える ex) x1, 合計2
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.character) {
+	for isLetter(l.character) || unicode.IsDigit(l.character) {
 		l.readChar()
 	}
 	return l.input[position:l.position] // 識別子の初めの文字から終わりの文字まで（識別子自体）
 }
-// 小文字/大文字のアルファベット、アンダースコアを英字としている
-func isLetter(character byte) bool {
-	return ('a' <= character && character <= 'z') || ('A' <= character && character <= 'Z') || character == '_'
+// Unicodeの文字（漢字やかなも含む）、アンダースコアを英字としている
+func isLetter(character rune) bool {
+	return unicode.IsLetter(character) || character == '_'
 }
 
-func (l *Lexer) readNumber() string {
+/*
+"..."を読んで、エスケープを展開した中身を返す
+閉じる"の上で止まる（NextTokenのreadCharで読み飛ばす）
+閉じていない場合や不正なエスケープはfalseとソースそのままを返す
+*/
+func (l *Lexer) readString(start token.Pos) (string, bool) {
+	var out strings.Builder
+	ok := true
+	for {
+		l.readChar()
+		switch l.character {
+		case '"':
+			if !ok {
+				return l.input[start.Offset:l.nextPosition], false
+			}
+			return out.String(), true
+		case 0:
+			l.errors[start.Offset] = Error{ Pos: start, Msg: "unterminated string literal" } // 開きの"を指す
+			return l.input[start.Offset:l.position], false
+		case '\\':
+			escPos := l.currentPos()
+			l.readChar()
+			switch l.character {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			case '"':
+				out.WriteByte('"')
+			case '\\':
+				out.WriteByte('\\')
+			case 'u':
+				r, valid := l.readUnicodeEscape()
+				if !valid {
+					ok = false
+					l.addError(start, escPos, "invalid unicode escape in string literal")
+					continue
+				}
+				out.WriteRune(r)
+			case 0:
+				l.errors[start.Offset] = Error{ Pos: start, Msg: "unterminated string literal" }
+				return l.input[start.Offset:l.position], false
+			default:
+				ok = false
+				l.addError(start, escPos, "unknown escape sequence \\%c in string literal", l.character)
+			}
+		default:
+			out.WriteRune(l.character)
+		}
+	}
+}
+
+// \u{1F600} の { から } まで、16進数で1〜6桁
+func (l *Lexer) readUnicodeEscape() (rune, bool) {
+	if l.peekChar() != '{' {
+		return 0, false
+	}
+	l.readChar()
+	digits := l.nextPosition
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+	}
+	hex := l.input[digits:l.nextPosition]
+	if l.peekChar() != '}' || len(hex) == 0 || len(hex) > 6 {
+		return 0, false
+	}
+	l.readChar()
+
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || code > 0x10FFFF || (0xD800 <= code && code <= 0xDFFF) {
+		return 0, false
+	}
+	return rune(code), true
+}
+
+func isHexDigit(character rune) bool {
+	return isDigit(character) || ('a' <= character && character <= 'f') || ('A' <= character && character <= 'F')
+}
+
+/*
+数値リテラル、桁の区切りに_が使える
+  0x1F 0o17 0b1010 -> INT（接頭辞の後ろの英数字はまとめて読み、正しいかは構文解析で確かめる）
+  1_000_000 -> INT
+  3.14 1e-9 2.5E+3 -> FLOAT
+*/
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
-	for isDigit(l.character) {
+
+	if l.character == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
+		l.readChar()
 		l.readChar()
+		for isLetter(l.character) || isDigit(l.character) {
+			l.readChar()
+		}
+		return l.input[position:l.position], token.INT
+	}
+
+	tokenType := token.TokenType(token.INT)
+	l.readDigits()
+
+	if l.character == '.' && isDigit(l.peekChar()) { // 1.foo のようなものは小数にしない
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.character == 'e' || l.character == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekSecondChar())) {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.character == '+' || l.character == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
+	for isDigit(l.character) || l.character == '_' {
+		l.readChar()
+	}
+}
+
+// 二つ先の文字
+func (l *Lexer) peekSecondChar() rune {
+	if l.nextPosition >= len(l.input) {
+		return 0
+	}
+	_, width := utf8.DecodeRuneInString(l.input[l.nextPosition:])
+	if l.nextPosition+width >= len(l.input) {
+		return 0
 	}
-	return l.input[position:l.position]
+	r, _ := utf8.DecodeRuneInString(l.input[l.nextPosition+width:])
+	return r
 }
-func isDigit(character byte) bool {
+// 数値リテラルはASCIIの数字だけ
+func isDigit(character rune) bool {
 	return '0' <= character && character <= '9'
 }
 
 // 空白は無視
 func (l *Lexer) skipWhiteSpace() {
-	for l.character == ' ' || l.character == '\t' || l.character == '\n' || l.character == '\r' {
+	for isWhiteSpace(l.character) {
 		l.readChar()
 	}
+}
+
+func isWhiteSpace(character rune) bool {
+	return character == ' ' || character == '\t' || character == '\n' || character == '\r'
 }
\ No newline at end of file
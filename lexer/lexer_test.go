@@ -13,7 +13,7 @@ func TestNextToken(t *testing.T) { // Tはユニットテスト
 		    x + y;
 		};
 		let result = add(five, ten);
-		!-/*5;
+		!-/ *5; // /* はコメントの始まりになる
 		5<10>5;
 		if(5<10) {
 		    return true;
@@ -22,6 +22,8 @@ func TestNextToken(t *testing.T) { // Tはユニットテスト
 		}
 		10 == 10;
 		10 != 9;
+		[1, 2];
+		{"foo": "bar"}
 	`
 
 	tests := []struct {
@@ -101,6 +103,17 @@ func TestNextToken(t *testing.T) { // Tはユニットテスト
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
 		{token.EOF, ""},
 	}
 
@@ -117,4 +130,428 @@ func TestNextToken(t *testing.T) { // Tはユニットテスト
 	
 }
 
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x == 10\r\n\tfoo"
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+		expectedOffset int
+	}{
+		{token.LET, 1, 1, 0},
+		{token.IDENT, 1, 5, 4},
+		{token.ASSIGN, 1, 7, 6},
+		{token.INT, 1, 9, 8},
+		{token.SEMICOLON, 1, 10, 9},
+		{token.IDENT, 2, 3, 13},
+		{token.EQ, 2, 5, 15},
+		{token.INT, 2, 8, 18},
+		{token.IDENT, 3, 2, 23},
+		{token.EOF, 3, 5, 26},
+		{token.EOF, 3, 5, 26}, // EOFの後は位置が変わらない
+	}
+
+	l := NewFile("test.monkey", input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn || tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d(%d), got=%d:%d(%d)", i,
+				tt.expectedLine, tt.expectedColumn, tt.expectedOffset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+		if tok.Pos.Filename != "test.monkey" {
+			t.Fatalf("tests[%d] - filename wrong. got=%q", i, tok.Pos.Filename)
+		}
+	}
+
+	if pos := (token.Pos{Filename: "a.monkey", Line: 2, Column: 3}).String(); pos != "a.monkey:2:3" {
+		t.Errorf("Pos.String() wrong. got=%q", pos)
+	}
+}
+
+func TestStringTokens(t *testing.T) {
+	input := `"foobar" "foo bar" "a\nb\t\"c\"\\" "\u{48}\u{65}\u{1F600}" ""`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, "foobar"},
+		{token.STRING, "foo bar"},
+		{token.STRING, "a\nb\t\"c\"\\"},
+		{token.STRING, "He\U0001F600"},
+		{token.STRING, ""},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenliteral wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedMsg    string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"let s = \"abc;\nlet t = 1;", "unterminated string literal", 1, 9},
+		{`"abc\`, "unterminated string literal", 1, 1},
+		{`  "a\qb"`, `unknown escape sequence \q in string literal`, 1, 5},
+		{`"\u{110000}"`, "invalid unicode escape in string literal", 1, 2},
+		{`"\u41"`, "invalid unicode escape in string literal", 1, 2},
+		{"@", `illegal character '@'`, 1, 1},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		var tok token.Token
+		for tok = l.NextToken(); tok.Type != token.ILLEGAL; tok = l.NextToken() {
+			if tok.Type == token.EOF {
+				t.Fatalf("tests[%d] - no ILLEGAL token for %q", i, tt.input)
+			}
+		}
+
+		err, ok := l.ErrorFor(tok)
+		if !ok {
+			t.Fatalf("tests[%d] - no error for token %+v", i, tok)
+		}
+		if err.Msg != tt.expectedMsg {
+			t.Errorf("tests[%d] - message wrong. expected=%q, got=%q", i, tt.expectedMsg, err.Msg)
+		}
+		if err.Pos.Line != tt.expectedLine || err.Pos.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d", i,
+				tt.expectedLine, tt.expectedColumn, err.Pos.Line, err.Pos.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+/* doc
+   comment */
+let x = 5; // five
+let y /* inline */ = x / 2;
+// footer
+`
+
+	type expectedToken struct {
+		typ      token.TokenType
+		literal  string
+		leading  []string
+		trailing []string
+	}
+	tests := []expectedToken{
+		{token.LET, "let", []string{"// header", "/* doc\n   comment */"}, nil},
+		{token.IDENT, "x", nil, nil},
+		{token.ASSIGN, "=", nil, nil},
+		{token.INT, "5", nil, nil},
+		{token.SEMICOLON, ";", nil, []string{"// five"}},
+		{token.LET, "let", nil, nil},
+		{token.IDENT, "y", nil, []string{"/* inline */"}},
+		{token.ASSIGN, "=", nil, nil},
+		{token.IDENT, "x", nil, nil},
+		{token.SLASH, "/", nil, nil},
+		{token.INT, "2", nil, nil},
+		{token.SEMICOLON, ";", nil, nil},
+		{token.EOF, "", []string{"// footer"}, nil},
+	}
+
+	texts := func(comments []token.Comment) []string {
+		var out []string
+		for _, c := range comments {
+			out = append(out, c.Text)
+		}
+		return out
+	}
+	equal := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.typ || tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - token wrong. expected=%q(%q), got=%q(%q)", i, tt.typ, tt.literal, tok.Type, tok.Literal)
+		}
+		if !equal(texts(tok.Leading), tt.leading) {
+			t.Errorf("tests[%d] - leading comments wrong. expected=%q, got=%q", i, tt.leading, texts(tok.Leading))
+		}
+		if !equal(texts(tok.Trailing), tt.trailing) {
+			t.Errorf("tests[%d] - trailing comments wrong. expected=%q, got=%q", i, tt.trailing, texts(tok.Trailing))
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	l := New("let x = 1;\n  /* oops")
+	var tok token.Token
+	for tok = l.NextToken(); tok.Type != token.ILLEGAL; tok = l.NextToken() {
+		if tok.Type == token.EOF {
+			t.Fatalf("no ILLEGAL token")
+		}
+	}
+
+	err, ok := l.ErrorFor(tok)
+	if !ok || err.Msg != "unterminated block comment" {
+		t.Fatalf("wrong error. got=%+v", err)
+	}
+	if err.Pos.Line != 2 || err.Pos.Column != 3 {
+		t.Errorf("position wrong. got=%d:%d", err.Pos.Line, err.Pos.Column)
+	}
+	if next := l.NextToken(); next.Type != token.EOF {
+		t.Errorf("expected EOF after unterminated comment. got=%q", next.Type)
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "let 合計 = 値1 + _x2;\n\"é\" ñ"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+		expectedOffset  int
+	}{
+		{token.LET, "let", 1, 0},
+		{token.IDENT, "合計", 5, 4},
+		{token.ASSIGN, "=", 8, 11},
+		{token.IDENT, "値1", 10, 13},
+		{token.PLUS, "+", 13, 18},
+		{token.IDENT, "_x2", 15, 20},
+		{token.SEMICOLON, ";", 18, 23},
+		{token.STRING, "é", 1, 25},
+		{token.IDENT, "ñ", 5, 30},
+		{token.EOF, "", 6, 32},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenliteral wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Column != tt.expectedColumn || tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - position wrong. expected=col %d(offset %d), got=col %d(offset %d)", i,
+				tt.expectedColumn, tt.expectedOffset, tok.Pos.Column, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestNumberTokens(t *testing.T) {
+	input := `0x1F 0o17 0b1010 1_000_000 3.14 1e-9 2.5E+3 7e2 0XfF 1.foo 2e x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0x1F"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.FLOAT, "7e2"},
+		{token.INT, "0XfF"},
+		{token.INT, "1"}, // 1.foo は小数ではない
+		{token.ILLEGAL, "."},
+		{token.IDENT, "foo"},
+		{token.INT, "2"}, // 指数の数字がなければ指数ではない
+		{token.IDENT, "e"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenliteral wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComparisonAndLogicalTokens(t *testing.T) {
+	input := `a <= b >= c && d || !e < f > g & h`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.AND, "&&"},
+		{token.IDENT, "d"},
+		{token.OR, "||"},
+		{token.BANG, "!"},
+		{token.IDENT, "e"},
+		{token.LT, "<"},
+		{token.IDENT, "f"},
+		{token.GT, ">"},
+		{token.IDENT, "g"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "h"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenliteral wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestArithmeticAndBitwiseTokens(t *testing.T) {
+	input := `a % b ** c * d & e | f ^ g << h >> i ~j`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "b"},
+		{token.POW, "**"},
+		{token.IDENT, "c"},
+		{token.ASTERISK, "*"},
+		{token.IDENT, "d"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "e"},
+		{token.BIT_OR, "|"},
+		{token.IDENT, "f"},
+		{token.BIT_XOR, "^"},
+		{token.IDENT, "g"},
+		{token.SHL, "<<"},
+		{token.IDENT, "h"},
+		{token.SHR, ">>"},
+		{token.IDENT, "i"},
+		{token.TILDE, "~"},
+		{token.IDENT, "j"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenliteral wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestAssignTokens(t *testing.T) {
+	input := `x = 1; x += 2; x -= 3; x *= 4; x /= 5; x == y; x**=2`
 
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "4"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EQ, "=="},
+		{token.IDENT, "y"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.POW, "**"}, // **= という演算子はない
+		{token.ASSIGN, "="},
+		{token.INT, "2"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenliteral wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	input := `while for in break continue macro quote whilst`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.MACRO, "macro"},
+		{token.IDENT, "quote"}, // quote/unquoteは評価器が名前で見分ける
+		{token.IDENT, "whilst"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - tokenliteral wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -8,6 +8,22 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fmt" { // monkey fmt でソースを整える
+		os.Exit(runFmt(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	if len(os.Args) > 1 { // monkey <file> でスクリプトを実行
+		src, err := os.ReadFile(os.Args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !repl.Run(os.Args[1], string(src), os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -15,4 +31,4 @@ func main() {
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
-}
\ No newline at end of file
+}
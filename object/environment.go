@@ -0,0 +1,70 @@
+package object
+
+import (
+	"io"
+	"os"
+)
+
+/*
+識別子と値の束縛を保持する
+見つからなければ外側（outer）のスコープを順にたどる
+*/
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+	out   io.Writer
+}
+
+func NewEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s, outer: nil}
+}
+
+// ブロックや関数本体のための内側のスコープを作る
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// 束縛は常に今のスコープに作る（外側の同名の束縛は隠れる）
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// 既にある束縛を、見つかったスコープで書き換える（なければfalse）
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return val, true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return nil, false
+}
+
+// putsなどの出力先を決める、内側のスコープはこれを引き継ぐ
+func (e *Environment) SetOutput(w io.Writer) {
+	e.out = w
+}
+
+// 設定がなければ外側をたどり、どこにもなければ標準出力
+func (e *Environment) Output() io.Writer {
+	if e.out != nil {
+		return e.out
+	}
+	if e.outer != nil {
+		return e.outer.Output()
+	}
+	return os.Stdout
+}
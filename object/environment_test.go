@@ -0,0 +1,92 @@
+package object
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestEnvironmentScopes(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("a", &Integer{Value: 1})
+	global.Set("b", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(global)
+	inner.Set("b", &Integer{Value: 20}) // 外側のbを隠す
+	inner.Set("c", &Integer{Value: 30})
+
+	tests := []struct {
+		env      *Environment
+		name     string
+		expected int64
+		found    bool
+	}{
+		{inner, "a", 1, true},
+		{inner, "b", 20, true},
+		{inner, "c", 30, true},
+		{global, "b", 2, true},
+		{global, "c", 0, false},
+		{inner, "d", 0, false},
+	}
+
+	for i, tt := range tests {
+		obj, ok := tt.env.Get(tt.name)
+		if ok != tt.found {
+			t.Errorf("tests[%d] - Get(%q) found=%t, want=%t", i, tt.name, ok, tt.found)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		integer, isInt := obj.(*Integer)
+		if !isInt || integer.Value != tt.expected {
+			t.Errorf("tests[%d] - Get(%q) wrong value. got=%s, want=%d", i, tt.name, obj.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("a", &Integer{Value: 1})
+
+	inner := NewEnclosedEnvironment(global)
+	inner.Set("b", &Integer{Value: 2})
+
+	// 見つかったスコープの束縛を書き換える
+	if _, ok := inner.Assign("a", &Integer{Value: 10}); !ok {
+		t.Fatalf("Assign(a) failed")
+	}
+	if _, ok := inner.Assign("b", &Integer{Value: 20}); !ok {
+		t.Fatalf("Assign(b) failed")
+	}
+	if _, ok := inner.Assign("c", &Integer{Value: 30}); ok {
+		t.Errorf("Assign(c) should fail for an undeclared name")
+	}
+
+	if obj, _ := global.Get("a"); obj.(*Integer).Value != 10 {
+		t.Errorf("global a wrong. got=%s", obj.Inspect())
+	}
+	if _, ok := global.Get("b"); ok {
+		t.Errorf("b leaked into the global scope")
+	}
+	if obj, _ := inner.Get("b"); obj.(*Integer).Value != 20 {
+		t.Errorf("inner b wrong. got=%s", obj.Inspect())
+	}
+	if _, ok := inner.Get("c"); ok {
+		t.Errorf("c should not be bound")
+	}
+}
+
+func TestEnvironmentOutput(t *testing.T) {
+	global := NewEnvironment()
+	if global.Output() != os.Stdout {
+		t.Errorf("default output should be os.Stdout")
+	}
+
+	var out bytes.Buffer
+	global.SetOutput(&out)
+	inner := NewEnclosedEnvironment(NewEnclosedEnvironment(global))
+	if inner.Output() != &out {
+		t.Errorf("inner scope should inherit the outer output")
+	}
+}
@@ -0,0 +1,90 @@
+package object
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	fnBody := &ast.BlockStatement{
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{
+				Expression: &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+			},
+		},
+	}
+
+	tests := []struct {
+		obj          Object
+		expectedType ObjectType
+		expected     string
+	}{
+		{&Integer{Value: -42}, INTEGER_OBJ, "-42"},
+		{&Float{Value: 3}, FLOAT_OBJ, "3.0"},
+		{&Float{Value: 1e-9}, FLOAT_OBJ, "1e-09"},
+		{TRUE, BOOLEAN_OBJ, "true"},
+		{FALSE, BOOLEAN_OBJ, "false"},
+		{NULL, NULL_OBJ, "null"},
+		{&String{Value: "hi"}, STRING_OBJ, "hi"},
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, ARRAY_OBJ, "[1, a]"},
+		{&ReturnValue{Value: &Integer{Value: 1}}, RETURN_VALUE_OBJ, "1"},
+		{&Error{Message: "boom"}, ERROR_OBJ, "ERROR: boom"},
+		{
+			&Function{
+				Parameters: []*ast.Identifier{
+					{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+					{Token: token.Token{Type: token.IDENT, Literal: "y"}, Value: "y"},
+				},
+				Body: fnBody,
+				Env:  NewEnvironment(),
+			},
+			FUNCTION_OBJ,
+			"fn(x, y) {\nx\n}",
+		},
+		{&Builtin{Fn: func(env *Environment, args ...Object) Object { return NULL }}, BUILTIN_OBJ, "builtin function"},
+		{&Quote{Node: fnBody.Statements[0]}, QUOTE_OBJ, "QUOTE(x)"},
+		{
+			&Macro{
+				Parameters: []*ast.Identifier{{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"}},
+				Body:       fnBody,
+				Env:        NewEnvironment(),
+			},
+			MACRO_OBJ,
+			"macro(x) {\nx\n}",
+		},
+	}
+
+	for i, tt := range tests {
+		if tt.obj.Type() != tt.expectedType {
+			t.Errorf("tests[%d] - wrong type. expected=%q, got=%q", i, tt.expectedType, tt.obj.Type())
+		}
+		if tt.obj.Inspect() != tt.expected {
+			t.Errorf("tests[%d] - wrong Inspect(). expected=%q, got=%q", i, tt.expected, tt.obj.Inspect())
+		}
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+
+	// 型が違えば同じ値でも別のキー
+	if (&Integer{Value: 1}).HashKey() == TRUE.HashKey() {
+		t.Errorf("integer 1 and true have same hash keys")
+	}
+}
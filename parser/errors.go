@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+	"strings"
+)
+
+// エラーの種類、メッセージの文言が変わっても変わらない
+type ErrorCode string
+
+const (
+	ErrUnexpectedToken ErrorCode = "P001" // 期待したトークンと違う
+	ErrNoPrefixParseFn ErrorCode = "P002" // 式の先頭に来られないトークン
+	ErrInvalidInteger  ErrorCode = "P003" // 整数として読めない ex) 0b102, 1__0
+	ErrIllegalToken    ErrorCode = "P004" // 字句解析のエラー（閉じていない文字列など）
+	ErrIntegerOverflow ErrorCode = "P005" // int64に収まらない整数
+	ErrInvalidFloat    ErrorCode = "P006" // 小数として読めない、範囲外
+	ErrNotInLoop       ErrorCode = "P007" // ループの外のbreak/continue
+	ErrInvalidAssign   ErrorCode = "P008" // 代入できない左辺 ex) 1 = 2, f() += 1
+)
+
+// 構文解析のエラー、どこで何が起きたかを持つ
+type ParseError struct {
+	Code     ErrorCode
+	Pos      token.Pos
+	Expected token.TokenType // 期待していたトークン、なければ空
+	Actual   token.TokenType // 実際に来たトークン
+	Msg      string
+}
+
+// 以前の[]stringのエラーと同じ文言
+func (e *ParseError) Error() string { return e.Msg }
+
+// エラーの行を取り出して、位置に^を付けて表示する
+//
+//	test.monkey:1:7: expected next token to be =, got INT instead [P001]
+//	let x 5;
+//	      ^
+func (e *ParseError) Render(src string) string {
+	var out strings.Builder
+	if e.Pos.IsValid() {
+		out.WriteString(e.Pos.String() + ": ")
+	}
+	out.WriteString(fmt.Sprintf("%s [%s]", e.Msg, e.Code))
+
+	line, ok := sourceLine(src, e.Pos.Line)
+	if !ok {
+		return out.String()
+	}
+	out.WriteString("\n" + line + "\n")
+
+	// 列は文字単位、タブはそのまま残して^の位置をそろえる
+	col := e.Pos.Column - 1
+	for _, c := range line {
+		if col <= 0 {
+			break
+		}
+		if c == '\t' {
+			out.WriteByte('\t')
+		} else {
+			out.WriteByte(' ')
+		}
+		col--
+	}
+	out.WriteString("^")
+	return out.String()
+}
+
+func sourceLine(src string, n int) (string, bool) {
+	if n < 1 {
+		return "", false
+	}
+	lines := strings.Split(src, "\n")
+	if n > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[n-1], "\r"), true
+}
@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"monkey/lexer"
+	"monkey/token"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedCode     ErrorCode
+		expectedExpected token.TokenType
+		expectedActual   token.TokenType
+		expectedLine     int
+		expectedColumn   int
+		expectedMessage  string
+	}{
+		{"let x 5;", ErrUnexpectedToken, token.ASSIGN, token.INT, 1, 7,
+			"expected next token to be =, got INT instead"},
+		{"let = 5;", ErrUnexpectedToken, token.IDENT, token.ASSIGN, 1, 5,
+			"expected next token to be IDENT, got = instead"},
+		{"\n  if (x { 1 }", ErrUnexpectedToken, token.RPAREN, token.LBRACE, 2, 9,
+			"expected next token to be ), got { instead"},
+		{"1 + ;", ErrNoPrefixParseFn, "", token.SEMICOLON, 1, 5,
+			"no prefix parse function for ; found"},
+		{"99999999999999999999", ErrIntegerOverflow, "", token.INT, 1, 19,
+			"integer literal \"99999999999999999999\" overflows int64"},
+		{"let x = 1 + 0x1_0000_0000_0000_0000;", ErrIntegerOverflow, "", token.INT, 1, 35,
+			"integer literal \"0x1_0000_0000_0000_0000\" overflows int64"},
+		{"0b102", ErrInvalidInteger, "", token.INT, 1, 1,
+			"could not parse \"0b102\" as integer"},
+		{"1__0", ErrInvalidInteger, "", token.INT, 1, 1,
+			"could not parse \"1__0\" as integer"},
+		{"  1e999", ErrInvalidFloat, "", token.FLOAT, 1, 3,
+			"float literal \"1e999\" out of range"},
+		{"let s = 1;\nlet t = \"abc;", ErrIllegalToken, "", token.ILLEGAL, 2, 9,
+			"unterminated string literal"},
+		{"f(x) += 1;", ErrInvalidAssign, "", token.PLUS_ASSIGN, 1, 6,
+			"cannot assign to f(x)"},
+		{"let a = 1;\n1 = a", ErrInvalidAssign, "", token.ASSIGN, 2, 3,
+			"cannot assign to 1"},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) == 0 {
+			t.Fatalf("tests[%d] - no errors for %q", i, tt.input)
+		}
+
+		err := errors[0]
+		if err.Code != tt.expectedCode {
+			t.Errorf("tests[%d] - code wrong. expected=%s, got=%s", i, tt.expectedCode, err.Code)
+		}
+		if err.Expected != tt.expectedExpected {
+			t.Errorf("tests[%d] - expected token wrong. expected=%q, got=%q", i, tt.expectedExpected, err.Expected)
+		}
+		if err.Actual != tt.expectedActual {
+			t.Errorf("tests[%d] - actual token wrong. expected=%q, got=%q", i, tt.expectedActual, err.Actual)
+		}
+		if err.Pos.Line != tt.expectedLine || err.Pos.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d", i,
+				tt.expectedLine, tt.expectedColumn, err.Pos.Line, err.Pos.Column)
+		}
+		if err.Error() != tt.expectedMessage {
+			t.Errorf("tests[%d] - message wrong. expected=%q, got=%q", i, tt.expectedMessage, err.Error())
+		}
+		if p.Errors()[0] != tt.expectedMessage { // 文字列のエラーも同じ
+			t.Errorf("tests[%d] - Errors()[0] wrong. expected=%q, got=%q", i, tt.expectedMessage, p.Errors()[0])
+		}
+	}
+}
+
+// 左辺の解析が失敗したときは代入のエラーを重ねない
+func TestInvalidAssignAfterError(t *testing.T) {
+	input := "f(x = 1);"
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.ParseErrors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%d (%q)", len(errors), p.Errors())
+	}
+	if errors[0].Code != ErrUnexpectedToken {
+		t.Errorf("code wrong. expected=%s, got=%s", ErrUnexpectedToken, errors[0].Code)
+	}
+}
+
+func TestRenderParseError(t *testing.T) {
+	input := "let a = 1;\n\tlet x 5;"
+	l := lexer.NewFile("test.monkey", input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.ParseErrors()
+	if len(errors) == 0 {
+		t.Fatalf("no errors for %q", input)
+	}
+
+	expected := "test.monkey:2:8: expected next token to be =, got INT instead [P001]\n" +
+		"\tlet x 5;\n" +
+		"\t      ^"
+	if got := errors[0].Render(input); got != expected {
+		t.Errorf("Render() wrong.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `
+let x 5;
+let y = 10;
+let = 3;
+let add = fn(a, b) { a + b };
+if (y { 1 }
+let w = y;
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	expectedErrors := []string{
+		"expected next token to be =, got INT instead",
+		"expected next token to be IDENT, got = instead",
+		"expected next token to be ), got { instead",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expectedErrors) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%q)", len(expectedErrors), len(errors), errors)
+	}
+	for i, msg := range expectedErrors {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, errors[i])
+		}
+	}
+
+	// エラーのない文は残る
+	expected := "let y = 10;let add = fn(a, b) (a + b);let w = y;"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestErrorRecoveryInBlock(t *testing.T) {
+	input := `
+let f = fn() {
+	let a 1;
+	let b = 2;
+	return b
+};
+let g = 1 +;
+f();
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 2 {
+		t.Fatalf("wrong number of errors. expected=2, got=%d (%q)", len(p.Errors()), p.Errors())
+	}
+
+	expected := "let f = fn() let b = 2;return b;;f()"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+// 式の途中でエラーになっても、同期するまで後続のエラーは出さない
+func TestErrorRecoveryInExpression(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expected       string
+	}{
+		{
+			"let a = ;\nlet = 5;\nlet c = (1 + ;\nlet d = 4;",
+			[]string{
+				"no prefix parse function for ; found",
+				"expected next token to be IDENT, got = instead",
+				"no prefix parse function for ; found",
+			},
+			"let d = 4;",
+		},
+		{"(1 + ;\nlet d = 4;", []string{"no prefix parse function for ; found"}, "let d = 4;"},
+		{"[1, ;\nlet d = 4;", []string{"no prefix parse function for ; found"}, "let d = 4;"},
+		{"let b = {1: };\nlet d = 4;", []string{"no prefix parse function for } found"}, "let d = 4;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%d, got=%d (%q)",
+				tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+		for i, msg := range tt.expectedErrors {
+			if errors[i] != msg {
+				t.Errorf("errors[%d] wrong for %q. expected=%q, got=%q", i, tt.input, msg, errors[i])
+			}
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestRenderParseErrorUnicode(t *testing.T) {
+	input := `let 合計 "円";`
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.ParseErrors()
+	if len(errors) == 0 {
+		t.Fatalf("no errors for %q", input)
+	}
+
+	expected := "1:8: expected next token to be =, got STRING instead [P001]\n" +
+		"let 合計 \"円\";\n" +
+		"       ^"
+	if got := errors[0].Render(input); got != expected {
+		t.Errorf("Render() wrong.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+)
+
+// while (<condition>) { <body> }
+func (p *Parser) parseWhileStatement() ast.Statement {
+	defer p.untrace(p.trace("parseWhileStatement"))
+
+	stmt := &ast.WhileStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(token.LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
+
+/*
+for (<init>; <condition>; <post>) { <body> }
+for (<variable> in <iterable>) { <body> }
+( の次が 識別子 in なら for-in
+*/
+func (p *Parser) parseForStatement() ast.Statement {
+	defer p.untrace(p.trace("parseForStatement"))
+
+	tok := p.curToken
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.IN) {
+		return p.parseForInStatement(tok)
+	}
+
+	stmt := &ast.ForStatement{Token: tok}
+
+	// init、letは ; まで読んでいることがある
+	if !p.curTokenIs(token.SEMICOLON) {
+		stmt.Init = p.parseSimpleStatement()
+		if stmt.Init == nil {
+			return nil
+		}
+		if !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	// condition
+	p.nextToken()
+	if !p.curTokenIs(token.SEMICOLON) {
+		stmt.Condition = p.parseExpression(token.LOWEST)
+		if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	// post
+	p.nextToken()
+	if !p.curTokenIs(token.RPAREN) {
+		stmt.Post = p.parseSimpleStatement()
+		if stmt.Post == nil {
+			return nil
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
+
+func (p *Parser) parseForInStatement(tok token.Token) ast.Statement {
+	stmt := &ast.ForInStatement{Token: tok}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	p.nextToken() // in
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(token.LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
+
+// for文の初期化と更新に書ける文、let・代入・式
+func (p *Parser) parseSimpleStatement() ast.Statement {
+	if p.curTokenIs(token.LET) {
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	}
+	return p.parseExpressionOrAssignStatement()
+}
+
+// break/continueが書けるのはループの本体の中だけ
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseBreakStatement() ast.Statement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+	p.checkInLoop()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() ast.Statement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+	p.checkInLoop()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) checkInLoop() {
+	if p.loopDepth > 0 {
+		return
+	}
+	p.addError(&ParseError{
+		Code:   ErrNotInLoop,
+		Pos:    p.curToken.Pos,
+		Actual: p.curToken.Type,
+		Msg:    fmt.Sprintf("%s is not in a loop", p.curToken.Literal),
+	})
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"testing"
+)
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { x; break; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.WhileStatement. got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", 10) {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Errorf("body.Statements[1] is not *ast.BreakStatement. got=%T", stmt.Body.Statements[1])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (let i = 0; i < n; i = i + 1) { f(i) }", "for (let i = 0; (i < n); i = (i + 1)) f(i)"},
+		{"for (i; i < n; i) { continue; }", "for (i; (i < n); i) continue;"},
+		{"for (;;) { break }", "for (; ; ) break;"},
+		{"for (let i = 0;; ) { }", "for (let i = 0; ; ) "},
+		{"for (i = 0; i < n; i += 1) { }", "for (i = 0; (i < n); i += 1) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+		if _, ok := program.Statements[0].(*ast.ForStatement); !ok {
+			t.Fatalf("stmt is not *ast.ForStatement. got=%T", program.Statements[0])
+		}
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	input := `for (x in [1, 2]) { puts(x) }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ForInStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.ForInStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+	if stmt.Iterable.String() != "[1, 2]" {
+		t.Errorf("stmt.Iterable wrong. got=%q", stmt.Iterable.String())
+	}
+	if program.String() != "for (x in [1, 2]) puts(x)" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"break;", []string{"break is not in a loop"}},
+		{"if (true) { continue }", []string{"continue is not in a loop"}},
+		{"while (true) { fn() { break; } }", []string{"break is not in a loop"}},
+		{"while (true) { if (x) { break; } fn() { while (y) { continue } } }", []string{}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.ParseErrors()
+		if len(errors) != len(tt.expected) {
+			t.Fatalf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+		}
+		for i, msg := range tt.expected {
+			if errors[i].Msg != msg || errors[i].Code != ErrNotInLoop {
+				t.Errorf("errors[%d] wrong. expected=%q, got=%q (%s)", i, msg, errors[i].Msg, errors[i].Code)
+			}
+		}
+	}
+}
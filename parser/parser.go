@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
@@ -16,34 +18,65 @@ type (
 // トークンを見ていってASTを作成する
 type Parser struct {
 	l *lexer.Lexer
-	errors []string 
+	errors []*ParseError
+	recovered int // 同期済みのエラーの数
+	loopDepth int // いくつのループの中にいるか、関数に入ると0に戻る
 	curToken token.Token // 今見ているトークン
 	peekToken token.Token // 次のトークン
 	prefixParsefns map[token.TokenType]prefixParsefn
 	infixParseFns map[token.TokenType]infixParseFn
+	traceOut io.Writer // nilならトレースしない
+	traceLevel int
 }
 
-func New(l *lexer.Lexer) *Parser {
-	p := &Parser{ l: l, errors: []string{} }
+// Newに渡す設定 ex) parser.New(l, parser.WithTrace(os.Stderr))
+type Option func(*Parser)
+
+func New(l *lexer.Lexer, opts ...Option) *Parser {
+	p := &Parser{ l: l, errors: []*ParseError{} }
+	for _, opt := range opts {
+		opt(p)
+	}
 	p.prefixParsefns = make(map[token.TokenType]prefixParsefn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POW, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseLogicalExpression)
+	p.registerInfix(token.OR, p.parseLogicalExpression)
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -54,13 +87,42 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{ Token: p.curToken, Value: p.curToken.Literal }
 }
 
+// エラーメッセージだけが欲しい場合
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		msgs[i] = err.Error()
+	}
+	return msgs
+}
+
+// 位置やエラーコードも含めたエラー
+func (p *Parser) ParseErrors() []*ParseError {
 	return p.errors
 }
 
+// 文の中では最初のエラーだけを残す、同期するまでの後続のエラーは捨てる
+func (p *Parser) addError(pe *ParseError) {
+	if p.failed() {
+		return
+	}
+	p.errors = append(p.errors, pe)
+}
+
+// 今の文でまだ同期していないエラーがあるか
+func (p *Parser) failed() bool {
+	return len(p.errors) > p.recovered
+}
+
 func (p *Parser) peekError(tt token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", tt, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Code: ErrUnexpectedToken,
+		Pos: p.peekToken.Pos,
+		Expected: tt,
+		Actual: p.peekToken.Type,
+		Msg: msg,
+	})
 }
 
 func (p *Parser) nextToken() {
@@ -73,7 +135,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for p.curToken.Type != token.EOF {
-		stmt := p.parseStatement()
+		stmt := p.parseStatementWithRecovery()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -82,14 +144,64 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// 文を一つ読む、途中でエラーが出たら文を捨てて次の文の手前まで読み飛ばす
+func (p *Parser) parseStatementWithRecovery() ast.Statement {
+	stmt := p.parseStatement()
+	if p.failed() { // ブロックの中で同期済みのエラーは数えない
+		p.synchronize()
+		p.recovered = len(p.errors)
+		return nil
+	}
+	return stmt
+}
+
+/*
+パニックモードの回復
+; を読むか、次が } let return のところまで進める（呼び出し側のnextTokenで次の文の先頭に来る）
+途中の { } は対応をとって読み飛ばす
+*/
+func (p *Parser) synchronize() {
+	depth := 0
+	for {
+		switch p.curToken.Type {
+		case token.EOF:
+			return
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 && (p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.LET) ||
+			p.peekTokenIs(token.RETURN) || p.peekTokenIs(token.EOF)) {
+			return
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET: // let ときているとき
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
-		return p.parseExpressionStatement()
+		return p.parseExpressionOrAssignStatement()
 	}
 }
 
@@ -104,7 +216,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		return nil
 	}
 
-	for !p.curTokenIs(token.SEMICOLON) { // とりあえず、=まで来たらおけ
+	p.nextToken()
+	stmt.Value = p.parseExpression(token.LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) { // セミコロンは省略できる
 		p.nextToken()
 	}
 
@@ -113,16 +228,26 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{ Token: p.curToken }
+
+	// return; や return } のように値がない場合
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	p.nextToken()
+	stmt.Value = p.parseExpression(token.LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
-	defer untrace(trace("parsePrefixExpression"))
+	defer p.untrace(p.trace("parsePrefixExpression"))
 	expression := &ast.PrefixExpression {
 		Token: p.curToken,
 		Operator: p.curToken.Literal,
@@ -135,7 +260,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 // 式の構文解析 ex) 1+2+3;
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	defer untrace(trace("parseExpressionStatement"))
+	defer p.untrace(p.trace("parseExpressionStatement"))
 	stmt := &ast.ExpressionStatement{ Token: p.curToken } // ex) [1]
 	stmt.Expression = p.parseExpression(token.LOWEST) //　①
 	if p.peekTokenIs(token.SEMICOLON) {
@@ -144,13 +269,65 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
+// 代入の演算子
+var assignOperators = map[token.TokenType]bool{
+	token.ASSIGN:          true,
+	token.PLUS_ASSIGN:     true,
+	token.MINUS_ASSIGN:    true,
+	token.ASTERISK_ASSIGN: true,
+	token.SLASH_ASSIGN:    true,
+}
+
+/*
+式を読んで、次が = や += なら代入文にする ex) x = 1; arr[0] += 2;
+=は優先順位を持たないので、parseExpressionは左辺だけ読んで止まる
+*/
+func (p *Parser) parseExpressionOrAssignStatement() ast.Statement {
+	stmt := p.parseExpressionStatement()
+	if !assignOperators[p.peekToken.Type] {
+		return stmt
+	}
+
+	assign := &ast.AssignStatement{Target: stmt.Expression}
+	p.nextToken()
+	assign.Token = p.curToken
+	assign.Operator = p.curToken.Literal
+
+	switch assign.Target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		// 左辺の解析が既に失敗していれば、そのエラーだけを出す
+		if assign.Target != nil && !p.failed() {
+			p.addError(&ParseError{
+				Code:   ErrInvalidAssign,
+				Pos:    p.curToken.Pos,
+				Actual: p.curToken.Type,
+				Msg:    fmt.Sprintf("cannot assign to %s", assign.Target.String()),
+			})
+		}
+	}
+
+	p.nextToken()
+	assign.Value = p.parseExpression(token.LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return assign
+}
+
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(&ParseError{
+		Code: ErrNoPrefixParseFn,
+		Pos: p.curToken.Pos,
+		Actual: t,
+		Msg: msg,
+	})
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression { // ②、⑤
-	defer untrace(trace("parseExpression"))
+	defer p.untrace(p.trace("parseExpression"))
 	prefix := p.prefixParsefns[p.curToken.Type] // ex) parseIntegerLiteral、parseIntegerLiteral
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
@@ -159,7 +336,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression { // ②、⑤
 	leftExp := prefix() // *ast.IntegerLiteral（1）、*ast.IntegerLiteral (2)
 
 
-    for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() { // ex) p.nextTokenは+、p.nextTokenは+だがprecedenceも+
+    for !p.peekTokenIs(token.SEMICOLON) && p.peekBindsTighter(precedence) { // ex) p.nextTokenは+、p.nextTokenは+だがprecedenceも+
         infix := p.infixParseFns[p.peekToken.Type] // ex) parseInfixExpression
         if infix == nil {
             return leftExp
@@ -173,12 +350,63 @@ func (p *Parser) parseExpression(precedence int) ast.Expression { // ②、⑤
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
-	defer untrace(trace("parseIntegerLiteral"))
+	defer p.untrace(p.trace("parseIntegerLiteral"))
 	lit := &ast.IntegerLiteral{ Token: p.curToken }
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.integerOverflowError(p.curToken)
+		return nil
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(&ParseError{
+			Code: ErrInvalidInteger,
+			Pos: p.curToken.Pos,
+			Actual: p.curToken.Type,
+			Msg: msg,
+		})
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+// 桁あふれした桁の位置を指す ex) 9223372036854775808 なら最後の8
+func (p *Parser) integerOverflowError(tok token.Token) {
+	lit := tok.Literal
+	pos := tok.Pos
+	for i := range lit {
+		_, err := strconv.ParseInt(lit[:i+1], 0, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			pos.Column += i // 数値リテラルはASCIIなのでバイトと文字が一致する
+			pos.Offset += i
+			break
+		}
+	}
+
+	p.addError(&ParseError{
+		Code: ErrIntegerOverflow,
+		Pos: pos,
+		Actual: tok.Type,
+		Msg: fmt.Sprintf("integer literal %q overflows int64", lit),
+	})
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseFloatLiteral"))
+	lit := &ast.FloatLiteral{ Token: p.curToken }
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		if errors.Is(err, strconv.ErrRange) {
+			msg = fmt.Sprintf("float literal %q out of range", p.curToken.Literal)
+		}
+		p.addError(&ParseError{
+			Code: ErrInvalidFloat,
+			Pos: p.curToken.Pos,
+			Actual: p.curToken.Type,
+			Msg: msg,
+		})
 		return nil
 	}
 	lit.Value = value
@@ -215,10 +443,50 @@ var precedences = map[token.TokenType]int{
     token.NOT_EQ:   token.EQUALS,
     token.LT:       token.LESSGREATER,
     token.GT:       token.LESSGREATER,
+    token.LT_EQ:    token.LESSGREATER,
+    token.GT_EQ:    token.LESSGREATER,
+    token.AND:      token.LOGICALAND,
+    token.OR:       token.LOGICALOR,
     token.PLUS:     token.SUM,
     token.MINUS:    token.SUM,
     token.SLASH:    token.PRODUCT,
     token.ASTERISK: token.PRODUCT,
+    token.PERCENT:  token.PRODUCT,
+    token.POW:      token.POWER,
+    token.BIT_OR:   token.BITOR,
+    token.BIT_XOR:  token.BITXOR,
+    token.BIT_AND:  token.BITAND,
+    token.SHL:      token.SHIFT,
+    token.SHR:      token.SHIFT,
+    token.LPAREN:   token.CALL,
+    token.LBRACKET: token.INDEX,
+}
+
+// 右結合の演算子、a ** b ** c は a ** (b ** c)
+var rightAssociative = map[token.TokenType]bool{
+    token.POW: true,
+}
+
+// 二項演算子の優先順位、演算子でなければLOWEST（フォーマッタが括弧を決めるのに使う）
+func Precedence(tt token.TokenType) int {
+    if precedence, ok := precedences[tt]; ok { return precedence }
+    return token.LOWEST
+}
+
+func IsRightAssociative(tt token.TokenType) bool {
+    return rightAssociative[tt]
+}
+
+/*
+次の演算子が今の式を左辺として取るかどうか
+右結合の演算子は同じ優先順位でも取る（右辺を先にまとめる）
+*/
+func (p *Parser) peekBindsTighter(precedence int) bool {
+    peek := p.peekPrecedence()
+    if rightAssociative[p.peekToken.Type] {
+        return precedence <= peek
+    }
+    return precedence < peek
 }
 
 func (p *Parser) peekPrecedence() int { // ③
@@ -232,7 +500,7 @@ func (p *Parser) curPrecedence() int {
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression { // ④
-	defer untrace(trace("parseInfixExpression"))
+	defer p.untrace(p.trace("parseInfixExpression"))
     expression := &ast.InfixExpression{
         Token:    p.curToken,
         Operator: p.curToken.Literal,
@@ -250,6 +518,41 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression { // 
 }
 
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// 字句解析で不正とされたトークン、レキサーのエラーをそのまま報告する
+func (p *Parser) parseIllegal() ast.Expression {
+	pe := &ParseError{
+		Code: ErrIllegalToken,
+		Pos: p.curToken.Pos,
+		Actual: p.curToken.Type,
+		Msg: fmt.Sprintf("illegal token %q", p.curToken.Literal),
+	}
+	if lexErr, ok := p.l.ErrorFor(p.curToken); ok {
+		pe.Pos = lexErr.Pos
+		pe.Msg = lexErr.Msg
+	}
+	p.addError(pe)
+	return nil
+}
+
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseLogicalExpression"))
+	expression := &ast.LogicalExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -265,9 +568,10 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
-	defer untrace(trace("parseIfExpression"))
+	defer p.untrace(p.trace("parseIfExpression"))
 
 	// if (<condition>) { <consequence> } else { <alternative> }
+	// if (<condition>) { <consequence> } else if ...
 	exp := &ast.IfExpression{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
@@ -294,6 +598,14 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	}
 
 	p.nextToken()
+	if p.peekTokenIs(token.IF) {
+		exp.Alternative = p.parseElseIf()
+		if exp.Alternative == nil {
+			return nil
+		}
+		return exp
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -303,15 +615,31 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return exp
 }
 
+// else if のifを、それだけを持つブロックに包む
+func (p *Parser) parseElseIf() *ast.BlockStatement {
+	p.nextToken()
+	tok := p.curToken
+
+	elseIf := p.parseIfExpression()
+	if elseIf == nil {
+		return nil
+	}
+
+	return &ast.BlockStatement{
+		Token:      tok,
+		Statements: []ast.Statement{&ast.ExpressionStatement{Token: tok, Expression: elseIf}},
+	}
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
-	defer untrace(trace("parseBlockStatement"))
+	defer p.untrace(p.trace("parseBlockStatement"))
 
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseStatementWithRecovery()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
@@ -319,4 +647,178 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	}
 
 	return block
-}
\ No newline at end of file
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseFunctionLiteral"))
+
+	// fn(<parameters>) { <body> }
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+	lit.Parameters, lit.Body = p.parseParametersAndBody()
+	if lit.Body == nil {
+		return nil
+	}
+	return lit
+}
+
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseMacroLiteral"))
+
+	// macro(<parameters>) { <body> }
+	lit := &ast.MacroLiteral{Token: p.curToken}
+	lit.Parameters, lit.Body = p.parseParametersAndBody()
+	if lit.Body == nil {
+		return nil
+	}
+	return lit
+}
+
+// fnとmacroの共通部分、(<parameters>) { <body> }
+func (p *Parser) parseParametersAndBody() ([]*ast.Identifier, *ast.BlockStatement) {
+	if !p.expectPeek(token.LPAREN) {
+		return nil, nil
+	}
+
+	parameters := p.parseFunctionParameters()
+	if parameters == nil {
+		return nil, nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil, nil
+	}
+
+	// 関数の中から外側のループをbreakすることはできない
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
+	body := p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
+
+	return parameters, body
+}
+
+// (x, y, z) の中身、空の場合もある
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return identifiers
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return identifiers
+}
+
+// add(1, 2) の ( を中置演算子とみなす、functionは左側の式
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseCallExpression"))
+
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
+	return exp
+}
+
+// カンマ区切りの式をendまで読む、引数と配列の要素で使う
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseExpression(token.LOWEST))
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list = append(list, p.parseExpression(token.LOWEST))
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseArrayLiteral"))
+
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	return array
+}
+
+// matrix[i] の [ を中置演算子とみなす
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseIndexExpression"))
+
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(token.LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+/*
+式の位置に来た { はハッシュリテラル
+ブロックはif/fnの後でparseBlockStatementが直接読むので、ここには来ない
+*/
+func (p *Parser) parseHashLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseHashLiteral"))
+
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []*ast.HashPair{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(token.LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(token.LOWEST)
+		hash.Pairs = append(hash.Pairs, &ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
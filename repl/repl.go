@@ -4,16 +4,22 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"monkey/evaluator"
 	"monkey/lexer"
-	"monkey/token"
+	"monkey/object"
+	"monkey/parser"
 )
 
 const PROMPT = ">> "
 
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
+	env := object.NewEnvironment() // 行をまたいで束縛を残す
+	env.SetOutput(out)
+	macroEnv := object.NewEnvironment()
+	macroEnv.SetOutput(out)
 	for {
-		fmt.Printf(PROMPT)
+		fmt.Fprintf(out, PROMPT)
 		scanned := scanner.Scan()
 		if !scanned {
 			return
@@ -21,8 +27,64 @@ func Start(in io.Reader, out io.Writer) {
 
 		line := scanner.Text()
 		l := lexer.New(line)
-		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-			fmt.Printf("%+v\n", tok)
-		} 
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			printParserErrors(out, line, p.ParseErrors())
+			continue
+		}
+
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			io.WriteString(out, err.Inspect())
+			io.WriteString(out, "\n")
+			continue
+		}
+
+		evaluated := evaluator.Eval(expanded, env)
+		if evaluated != nil {
+			io.WriteString(out, evaluated.Inspect())
+			io.WriteString(out, "\n")
+		}
+	}
+}
+
+// ファイルなどのソース全体を一度に評価する
+func Run(filename string, input string, out io.Writer) bool {
+	l := lexer.NewFile(filename, input)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(out, input, p.ParseErrors())
+		return false
+	}
+
+	macroEnv := object.NewEnvironment()
+	macroEnv.SetOutput(out)
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		io.WriteString(out, err.Inspect())
+		io.WriteString(out, "\n")
+		return false
+	}
+
+	env := object.NewEnvironment()
+	env.SetOutput(out)
+	evaluated := evaluator.Eval(expanded, env)
+	if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+		return false
+	}
+	return true
+}
+
+func printParserErrors(out io.Writer, src string, errors []*parser.ParseError) {
+	for _, err := range errors {
+		io.WriteString(out, err.Render(src)+"\n")
 	}
-}
\ No newline at end of file
+}
@@ -1,5 +1,10 @@
 package token
 
+import (
+	"strconv"
+	"strings"
+)
+
 /*
 トークンには種類がある（タイプ）
 トークンはタイプとリテラル（トークンの個別の情報）が必要
@@ -15,18 +20,63 @@ type TokenType string
 type Token struct {
 	Type TokenType
 	Literal string
+	Pos Pos // トークンの先頭の位置
+	Leading []Comment // トークンの前にあるコメント
+	Trailing []Comment // トークンと同じ行の後ろにあるコメント
+}
+
+// コメント、Textは // や /* */ を含めたソースそのまま
+type Comment struct {
+	Text string
+	Pos Pos
+}
+
+func (c Comment) IsBlock() bool { return strings.HasPrefix(c.Text, "/*") }
+
+// ソース上の位置、行と列は1始まり、Offsetは先頭からのバイト数（0始まり）
+type Pos struct {
+	Filename string
+	Line int
+	Column int
+	Offset int
+}
+
+// 行が0なら位置情報を持っていない（手で組み立てたトークンなど）
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+// file:line:column の形式、ファイル名がなければ line:column
+func (p Pos) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	s := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	if p.Filename != "" {
+		s = p.Filename + ":" + s
+	}
+	return s
 }
 
 // 優先順位
 const (
 	_int = iota
 	LOWEST
+	LOGICALOR // ||
+	LOGICALAND // &&
 	EQUALS
 	LESSGREATER
+	BITOR // |
+	BITXOR // ^
+	BITAND // &
+	SHIFT // << >>
 	SUM
-	PRODUCT
+	PRODUCT // * / %
 	PREFIX
+	POWER // **、-2 ** 2 は -(2 ** 2)
 	CALL
+	INDEX // array[index]
 )
 
 const (
@@ -35,6 +85,8 @@ const (
 
 	// リテラル
 	INT = "INT" // 5, 10
+	FLOAT = "FLOAT" // 3.14, 1e-9
+	STRING = "STRING" // "foo bar"
 
 	// キーワード
 	FUNCTION = "FUNCTION" // fn
@@ -44,39 +96,70 @@ const (
 	IF = "IF"
 	ELSE = "ELSE"
 	RETURN = "RETURN"
+	WHILE = "WHILE"
+	FOR = "FOR"
+	IN = "IN"
+	BREAK = "BREAK"
+	CONTINUE = "CONTINUE"
+	MACRO = "MACRO"
 
 	// 演算子
 	ASSIGN = "="
+	PLUS_ASSIGN = "+="
+	MINUS_ASSIGN = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN = "/="
 	PLUS = "+"
 	MINUS = "-"
 	BANG = "!"
 	ASTERISK = "*"
 	SLASH = "/"
+	PERCENT = "%"
+	POW = "**"
+	BIT_AND = "&"
+	BIT_OR = "|"
+	BIT_XOR = "^"
+	SHL = "<<"
+	SHR = ">>"
+	TILDE = "~"
 	LT = "<"
 	GT = ">"
 	EQ = "=="
 	NOT_EQ = "!="
+	LT_EQ = "<="
+	GT_EQ = ">="
+	AND = "&&"
+	OR = "||"
 
 	ILLEGAL = "ILLEGAL"
 	EOF = "EOF"
 
 	COMMA = ","
 	SEMICOLON = ";"
+	COLON = ":"
 
 	LPAREN = "("
 	RPAREN = ")"
 	LBRACE = "{"
 	RBRACE = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 )
 
 var keywords = map[string]TokenType { // 将来の追加を考えてvarにしている
 	"fn": FUNCTION,
+	"macro": MACRO,
 	"let": LET,
 	"true": TRUE,
 	"false": FALSE,
 	"if": IF,
 	"else": ELSE,
 	"return": RETURN,
+	"while": WHILE,
+	"for": FOR,
+	"in": IN,
+	"break": BREAK,
+	"continue": CONTINUE,
 }
 
 // 引数の識別子がキーワードかどうか